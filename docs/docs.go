@@ -15,9 +15,9 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/v1/rules": {
+        "/api/v1/audits": {
             "get": {
-                "description": "Retrieves a paginated list of compliance rules",
+                "description": "Retrieves validation decisions recorded in the audit log, newest first, using cursor-based pagination",
                 "consumes": [
                     "application/json"
                 ],
@@ -25,20 +25,56 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "rules"
+                    "audits"
                 ],
-                "summary": "List all rules",
+                "summary": "List audit entries",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Filter by customer ID",
+                        "name": "customerId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by transaction ID",
+                        "name": "transactionId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by decision status: approve, manual_review or reject",
+                        "name": "outcome",
+                        "in": "query"
+                    },
                     {
                         "type": "integer",
-                        "description": "Number of results per page (default 50)",
-                        "name": "size",
+                        "description": "Only entries whose trace includes this rule, also as a child of a composite rule",
+                        "name": "ruleId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created at or after (RFC3339)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created before (RFC3339)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor returned by the previous page",
+                        "name": "cursor",
                         "in": "query"
                     },
                     {
                         "type": "integer",
-                        "description": "Offset for pagination (default 0)",
-                        "name": "offset",
+                        "description": "Number of results per page (default 50, max 500)",
+                        "name": "size",
                         "in": "query"
                     }
                 ],
@@ -46,9 +82,15 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/rules.Rule"
+                            "$ref": "#/definitions/handlers.AuditPage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
                             }
                         }
                     },
@@ -62,9 +104,11 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "post": {
-                "description": "Creates a compliance rule in the system",
+            }
+        },
+        "/api/v1/audits/verify": {
+            "get": {
+                "description": "Recomputes the hash of every audit entry and reports the first entry whose content or link to the previous entry was changed",
                 "consumes": [
                     "application/json"
                 ],
@@ -72,25 +116,55 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "rules"
+                    "audits"
                 ],
-                "summary": "Create a new rule",
-                "parameters": [
-                    {
-                        "description": "Rule data",
-                        "name": "rule",
-                        "in": "body",
-                        "required": true,
+                "summary": "Verify the audit hash chain",
+                "responses": {
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/rules.Rule"
+                            "$ref": "#/definitions/service.ChainReport"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
+                }
+            }
+        },
+        "/api/v1/audits/{id}": {
+            "get": {
+                "description": "Retrieves an audit entry with its transaction, decision and full per-rule trace",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "audits"
+                ],
+                "summary": "Get audit entry by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Audit ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/rules.Rule"
+                            "$ref": "#/definitions/repository.AuditLog"
                         }
                     },
                     "400": {
@@ -102,6 +176,73 @@ const docTemplate = `{
                             }
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/cases": {
+            "get": {
+                "description": "Retrieves a paginated list of manual review cases, oldest first",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "cases"
+                ],
+                "summary": "List review cases",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Filter by status: open, assigned, approved or rejected",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by assigned analyst",
+                        "name": "assignee",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by transaction ID",
+                        "name": "transactionId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of results per page (default 50)",
+                        "name": "size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Offset for pagination (default 0)",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/repository.Case"
+                            }
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -114,9 +255,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/rules/{id}": {
+        "/api/v1/cases/{id}": {
             "get": {
-                "description": "Retrieves a specific compliance rule by its ID",
+                "description": "Retrieves a review case with its event history. Poll it to learn the final outcome.",
                 "consumes": [
                     "application/json"
                 ],
@@ -124,13 +265,13 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "rules"
+                    "cases"
                 ],
-                "summary": "Get rule by ID",
+                "summary": "Get review case by ID",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Rule ID",
+                        "description": "Case ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -140,7 +281,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/rules.Rule"
+                            "$ref": "#/definitions/repository.Case"
                         }
                     },
                     "400": {
@@ -160,11 +301,22 @@ const docTemplate = `{
                                 "type": "string"
                             }
                         }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
                     }
                 }
-            },
-            "put": {
-                "description": "Updates a compliance rule by ID",
+            }
+        },
+        "/api/v1/cases/{id}/approve": {
+            "post": {
+                "description": "Closes a case by approving the transaction and notifies the callback URL, if any",
                 "consumes": [
                     "application/json"
                 ],
@@ -172,24 +324,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "rules"
+                    "cases"
                 ],
-                "summary": "Update an existing rule",
+                "summary": "Approve a review case",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Rule ID",
+                        "description": "Case ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Updated rule data",
-                        "name": "rule",
+                        "description": "Actor and optional comment",
+                        "name": "action",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/rules.Rule"
+                            "$ref": "#/definitions/dto.CaseAction"
                         }
                     }
                 ],
@@ -197,7 +349,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/rules.Rule"
+                            "$ref": "#/definitions/repository.Case"
                         }
                     },
                     "400": {
@@ -209,6 +361,24 @@ const docTemplate = `{
                             }
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -219,9 +389,11 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "Deletes a compliance rule by ID",
+            }
+        },
+        "/api/v1/cases/{id}/assign": {
+            "post": {
+                "description": "Assigns an open case to an analyst, or reassigns it",
                 "consumes": [
                     "application/json"
                 ],
@@ -229,21 +401,33 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "rules"
+                    "cases"
                 ],
-                "summary": "Delete a rule",
+                "summary": "Assign a review case",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Rule ID",
+                        "description": "Case ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Actor and assignee",
+                        "name": "action",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.CaseAction"
+                        }
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content"
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/repository.Case"
+                        }
                     },
                     "400": {
                         "description": "Bad Request",
@@ -254,6 +438,24 @@ const docTemplate = `{
                             }
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -266,9 +468,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/validateTransaction": {
+        "/api/v1/cases/{id}/comments": {
             "post": {
-                "description": "Validates a transaction against compliance rules and returns a decision",
+                "description": "Adds an analyst comment to an open case",
                 "consumes": [
                     "application/json"
                 ],
@@ -276,17 +478,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "compliance"
+                    "cases"
                 ],
-                "summary": "Validate a transaction",
+                "summary": "Comment on a review case",
                 "parameters": [
                     {
-                        "description": "Transaction data",
-                        "name": "transaction",
+                        "type": "integer",
+                        "description": "Case ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Actor and comment",
+                        "name": "action",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/dto.Transaction"
+                            "$ref": "#/definitions/dto.CaseAction"
                         }
                     }
                 ],
@@ -294,7 +503,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/rules.Decision"
+                            "$ref": "#/definitions/repository.Case"
                         }
                     },
                     "400": {
@@ -306,6 +515,24 @@ const docTemplate = `{
                             }
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -317,79 +544,2206 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "dto.Transaction": {
-            "type": "object",
-            "properties": {
+        },
+        "/api/v1/cases/{id}/reject": {
+            "post": {
+                "description": "Closes a case by rejecting the transaction and notifies the callback URL, if any",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "cases"
+                ],
+                "summary": "Reject a review case",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Case ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Actor and optional comment",
+                        "name": "action",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.CaseAction"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/repository.Case"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/jobs": {
+            "post": {
+                "description": "Queues a file for background screening and returns the job. kind=transactions validates a JSON array or NDJSON of transactions, each audited like a single validation. kind=accounts checks account identifiers, with an optional party name, against the sanctions lists; the file is CSV (accId,name) or JSONL ({\"accId\": \"...\", \"name\": \"...\"} per line). The body may be the raw file or a multipart form with a \"file\" field. When callbackUrl is set, the finished job is POSTed to it; its host must be listed in CALLBACK_ALLOWED_HOSTS.",
+                "consumes": [
+                    "application/json",
+                    "application/x-ndjson",
+                    "text/csv",
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "jobs"
+                ],
+                "summary": "Submit a validation job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "transactions or accounts",
+                        "name": "kind",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Accounts file format: csv or jsonl",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Evaluation mode for transactions: all or first_failure",
+                        "name": "mode",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "URL notified when the job completes, on an allowed host",
+                        "name": "callbackUrl",
+                        "in": "query"
+                    },
+                    {
+                        "type": "file",
+                        "description": "File to upload (multipart)",
+                        "name": "file",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/repository.Job"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/jobs/{id}": {
+            "get": {
+                "description": "Reports a job's status (queued, running or completed) and progress: total items, items processed and items that could not be screened",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "jobs"
+                ],
+                "summary": "Get a validation job",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Job ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/repository.Job"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/jobs/{id}/results": {
+            "get": {
+                "description": "Streams the results screened so far as NDJSON, one line per item in file order: a batch validation result for transactions, or an account screening result for accounts",
+                "produces": [
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "jobs"
+                ],
+                "summary": "Download validation job results",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Job ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "One JSON result per line",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/reports/backtest": {
+            "post": {
+                "description": "Replays the transactions audited between from and to through the stored rules with the candidate changes applied, and compares the outcomes with the recorded decisions. Rules with the ID of a stored rule replace it, rules without an ID are added, and remove lists stored rules to leave out. Each transaction sees the rules in effect at its time and the transaction history recorded before its audit entry. Sanctions lists are the current ones, so sanctions results are approximate. Nothing is written.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "rules"
+                ],
+                "summary": "Backtest a candidate rule set",
+                "parameters": [
+                    {
+                        "description": "Candidate rules and time range",
+                        "name": "backtest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/service.Backtest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/service.BacktestReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/reports/shadow": {
+            "get": {
+                "description": "Summarizes shadow rule results recorded in the audit log over a time range: outcome pairs against the live decision, how often a shadow rule would have made the decision stricter, and sample audit IDs",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "rules"
+                ],
+                "summary": "Compare shadow rules with live decisions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Created at or after (RFC3339, default 24h before to)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created before (RFC3339, default now)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Only this shadow rule",
+                        "name": "ruleId",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/service.ShadowReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/rules": {
+            "get": {
+                "description": "Retrieves a paginated list of compliance rules",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "rules"
+                ],
+                "summary": "List all rules",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Number of results per page (default 50)",
+                        "name": "size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Offset for pagination (default 0)",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by effective dates: upcoming, active or expired",
+                        "name": "period",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Moment the period is judged at (RFC3339, default now)",
+                        "name": "at",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/rules.Rule"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Creates a compliance rule in the system",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "rules"
+                ],
+                "summary": "Create a new rule",
+                "parameters": [
+                    {
+                        "description": "Rule data",
+                        "name": "rule",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/rules.Rule"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/rules.Rule"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/rules/{id}": {
+            "get": {
+                "description": "Retrieves a specific compliance rule by its ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "rules"
+                ],
+                "summary": "Get rule by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Rule ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/rules.Rule"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Replaces a compliance rule by ID with the rule sent and records the result as a new version. Fields left out of the body are cleared.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "rules"
+                ],
+                "summary": "Update an existing rule",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Rule ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Updated rule data",
+                        "name": "rule",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/rules.Rule"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/rules.Rule"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Deletes a compliance rule by ID and records the deletion as a final version. A rule used by a composite rule cannot be deleted (409).",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "rules"
+                ],
+                "summary": "Delete a rule",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Rule ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/rules/{id}/rollback": {
+            "post": {
+                "description": "Restores the settings of an earlier version of a rule. The rollback is recorded as a new version.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "rules"
+                ],
+                "summary": "Roll back a rule",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Rule ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Version to restore",
+                        "name": "rollback",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.RuleRollback"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/rules.Rule"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/rules/{id}/versions": {
+            "get": {
+                "description": "Retrieves every version of a rule, newest first. Each update, rollback or delete adds a version; the one added on delete is marked deleted.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "rules"
+                ],
+                "summary": "List rule versions",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Rule ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/repository.RuleVersion"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/rules/{id}/versions/diff": {
+            "get": {
+                "description": "Lists the fields that changed between two versions of a rule",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "rules"
+                ],
+                "summary": "Diff two rule versions",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Rule ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Older version",
+                        "name": "from",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Newer version",
+                        "name": "to",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/service.RuleChange"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/sanctions": {
+            "get": {
+                "description": "Retrieves a paginated list of sanctions entries",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sanctions"
+                ],
+                "summary": "List sanctioned accounts",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Number of results per page (default 50)",
+                        "name": "size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Offset for pagination (default 0)",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/rules.Sanction"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Adds an account identifier to the sanctions list",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sanctions"
+                ],
+                "summary": "Add a sanctioned account",
+                "parameters": [
+                    {
+                        "description": "Sanction data",
+                        "name": "sanction",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/rules.Sanction"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/rules.Sanction"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/sanctions/bulk": {
+            "post": {
+                "description": "Imports account identifiers from a CSV (first column) or JSONL ({\"accId\": \"...\"} per line) upload in a single transaction. The body may be the raw file or a multipart form with a \"file\" field. The format is taken from the format parameter, the file extension or the Content-Type.",
+                "consumes": [
+                    "text/csv",
+                    "application/x-ndjson",
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sanctions"
+                ],
+                "summary": "Bulk upload sanctioned accounts",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "csv or jsonl",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "file",
+                        "description": "File to upload (multipart)",
+                        "name": "file",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/service.BulkResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/sanctions/{id}": {
+            "get": {
+                "description": "Retrieves a specific sanctions entry by its ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sanctions"
+                ],
+                "summary": "Get sanctions entry by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Sanction ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/rules.Sanction"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Removes an account from the sanctions list by entry ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sanctions"
+                ],
+                "summary": "Delete a sanctions entry",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Sanction ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/validateTransaction": {
+            "post": {
+                "description": "Validates a transaction against compliance rules and returns a decision. Status is approve, manual_review or reject; Approved is true only for approve. Results lists the outcome and risk score contribution of every rule evaluated; RiskScore is their 0-100 total. Repeating a transaction ID within the retention period returns the stored decision with the Idempotent-Replayed header set; a different payload under the same ID is a conflict.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "compliance"
+                ],
+                "summary": "Validate a transaction",
+                "parameters": [
+                    {
+                        "description": "Transaction data",
+                        "name": "transaction",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.Transaction"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Evaluation mode: all (every rule) or first_failure (stop at the first reject); defaults to the service setting",
+                        "name": "mode",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/rules.Decision"
+                        },
+                        "headers": {
+                            "Idempotent-Replayed": {
+                                "type": "string",
+                                "description": "true when the decision was stored by an earlier request"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/validateTransactions": {
+            "post": {
+                "description": "Validates up to the configured batch size of transactions, sent as a JSON array or as NDJSON (one transaction per line). Transactions are validated concurrently, each with its own decision and audit entry, and the results come back in input order. A transaction that cannot be validated gets an error in its result instead of a decision.",
+                "consumes": [
+                    "application/json",
+                    "application/x-ndjson"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "compliance"
+                ],
+                "summary": "Validate a batch of transactions",
+                "parameters": [
+                    {
+                        "description": "Transactions",
+                        "name": "transactions",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.Transaction"
+                            }
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Evaluation mode: all (every rule) or first_failure (stop at the first reject); defaults to the service setting",
+                        "name": "mode",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/service.BatchResult"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "dto.CaseAction": {
+            "type": "object",
+            "required": [
+                "actor"
+            ],
+            "properties": {
+                "actor": {
+                    "type": "string"
+                },
+                "assignee": {
+                    "type": "string"
+                },
+                "comment": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.RuleRollback": {
+            "type": "object",
+            "required": [
+                "version"
+            ],
+            "properties": {
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.Transaction": {
+            "type": "object",
+            "properties": {
                 "amount": {
+                    "type": "number"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "customerID": {
+                    "type": "string"
+                },
+                "fromAcc": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "metadata": {
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "timestamp": {
+                    "description": "Timestamp is when the transaction took place; rules in effect at that\nmoment are applied. Empty means now. Omitted when zero so entries\nrecorded before it existed keep their audit hash.",
+                    "type": "string"
+                },
+                "toAcc": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.AuditPage": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/repository.AuditLog"
+                    }
+                },
+                "nextCursor": {
+                    "type": "string"
+                }
+            }
+        },
+        "repository.AuditEvent": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "actor": {
+                    "type": "string"
+                },
+                "auditId": {
+                    "type": "integer"
+                },
+                "caseId": {
+                    "type": "integer"
+                },
+                "comment": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "case status after the event",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/repository.CaseStatus"
+                        }
+                    ]
+                }
+            }
+        },
+        "repository.AuditLog": {
+            "type": "object",
+            "properties": {
+                "customerId": {
+                    "type": "string"
+                },
+                "decision": {
+                    "$ref": "#/definitions/rules.Decision"
+                },
+                "decisionId": {
+                    "type": "integer"
+                },
+                "hash": {
+                    "type": "string"
+                },
+                "prevHash": {
+                    "type": "string"
+                },
+                "trace": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/rules.RuleResult"
+                    }
+                },
+                "transaction": {
+                    "$ref": "#/definitions/dto.Transaction"
+                },
+                "transactionId": {
+                    "type": "string"
+                }
+            }
+        },
+        "repository.Case": {
+            "type": "object",
+            "properties": {
+                "assignee": {
+                    "type": "string"
+                },
+                "auditId": {
+                    "type": "integer"
+                },
+                "callbackUrl": {
+                    "type": "string"
+                },
+                "customerId": {
+                    "type": "string"
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/repository.AuditEvent"
+                    }
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "resolvedAt": {
+                    "type": "string"
+                },
+                "resolvedBy": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/repository.CaseStatus"
+                },
+                "transactionId": {
+                    "type": "string"
+                }
+            }
+        },
+        "repository.CaseStatus": {
+            "type": "string",
+            "enum": [
+                "open",
+                "assigned",
+                "approved",
+                "rejected"
+            ],
+            "x-enum-varnames": [
+                "CaseOpen",
+                "CaseAssigned",
+                "CaseApproved",
+                "CaseRejected"
+            ]
+        },
+        "repository.Job": {
+            "type": "object",
+            "properties": {
+                "callbackUrl": {
+                    "type": "string"
+                },
+                "failed": {
+                    "description": "items that could not be screened",
+                    "type": "integer"
+                },
+                "finishedAt": {
+                    "type": "string"
+                },
+                "kind": {
+                    "$ref": "#/definitions/repository.JobKind"
+                },
+                "mode": {
+                    "description": "evaluation mode of a transactions job",
+                    "type": "string"
+                },
+                "processed": {
+                    "type": "integer"
+                },
+                "startedAt": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/repository.JobStatus"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "repository.JobKind": {
+            "type": "string",
+            "enum": [
+                "transactions",
+                "accounts"
+            ],
+            "x-enum-varnames": [
+                "JobTransactions",
+                "JobAccounts"
+            ]
+        },
+        "repository.JobStatus": {
+            "type": "string",
+            "enum": [
+                "queued",
+                "running",
+                "completed"
+            ],
+            "x-enum-varnames": [
+                "JobQueued",
+                "JobRunning",
+                "JobCompleted"
+            ]
+        },
+        "repository.RuleVersion": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "deleted": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "ruleId": {
+                    "type": "integer"
+                },
+                "snapshot": {
+                    "$ref": "#/definitions/rules.Rule"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "rules.CompositeOp": {
+            "type": "string",
+            "enum": [
+                "and",
+                "or",
+                "not"
+            ],
+            "x-enum-varnames": [
+                "CompositeAnd",
+                "CompositeOr",
+                "CompositeNot"
+            ]
+        },
+        "rules.Decision": {
+            "type": "object",
+            "properties": {
+                "approved": {
+                    "type": "boolean"
+                },
+                "caseID": {
+                    "description": "review case opened for a manual_review decision",
+                    "type": "integer"
+                },
+                "matches": {
+                    "description": "name screening candidates, best first",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/rules.NameMatch"
+                    }
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "replayed": {
+                    "description": "stored decision returned for a repeated transaction ID",
+                    "type": "boolean"
+                },
+                "results": {
+                    "description": "outcome of each rule evaluated, in order",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/rules.RuleResult"
+                    }
+                },
+                "riskScore": {
+                    "description": "0-100, the sum of the rule contributions in Results",
                     "type": "number",
                     "format": "float64"
                 },
-                "currency": {
+                "status": {
+                    "$ref": "#/definitions/rules.DecisionStatus"
+                }
+            }
+        },
+        "rules.DecisionStatus": {
+            "type": "string",
+            "enum": [
+                "approve",
+                "manual_review",
+                "reject"
+            ],
+            "x-enum-varnames": [
+                "StatusApprove",
+                "StatusManualReview",
+                "StatusReject"
+            ]
+        },
+        "rules.NameMatch": {
+            "type": "object",
+            "properties": {
+                "matchedName": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "sanctionId": {
+                    "type": "integer"
+                },
+                "score": {
+                    "type": "number"
+                },
+                "source": {
+                    "$ref": "#/definitions/rules.SanctionSource"
+                }
+            }
+        },
+        "rules.Rule": {
+            "type": "object",
+            "properties": {
+                "account": {
+                    "type": "string"
+                },
+                "children": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "description": {
                     "type": "string"
                 },
-                "customerID": {
+                "effectiveFrom": {
+                    "description": "EffectiveFrom and EffectiveTo bound when the rule is in effect; either\nmay be empty. Schedule optionally narrows that to recurring windows.",
                     "type": "string"
                 },
-                "fromAcc": {
+                "effectiveTo": {
                     "type": "string"
                 },
-                "id": {
+                "expression": {
+                    "description": "Expression settings: a condition over the transaction, such as\n` + "`" + `amount \u003e 5000 \u0026\u0026 metadata.channel == \"web\"` + "`" + `, that fails the rule when true.",
                     "type": "string"
                 },
-                "metadata": {
+                "group": {
+                    "type": "string"
+                },
+                "keyField": {
+                    "$ref": "#/definitions/rules.VelocityKey"
+                },
+                "matchThreshold": {
+                    "type": "number"
+                },
+                "maxAmount": {
+                    "type": "number"
+                },
+                "maxCount": {
+                    "type": "integer"
+                },
+                "metadataKey": {
+                    "description": "Name screening settings: MetadataKey names the transaction metadata entry\nholding the party name, MatchThreshold the minimum score (0-1) for a match.",
+                    "type": "string"
+                },
+                "minCount": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "operator": {
+                    "description": "Composite settings: Operator combines the rules listed in Children.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/rules.CompositeOp"
+                        }
+                    ]
+                },
+                "outcome": {
+                    "description": "Outcome overrides the status a failing rule produces (manual_review or\nreject). Empty keeps the rule type's default.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/rules.DecisionStatus"
+                        }
+                    ]
+                },
+                "schedule": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/rules.TimeWindow"
+                    }
+                },
+                "scope": {
+                    "description": "Scope limits the rule to one account, customer or customer segment,\nnamed by ScopeValue. Empty is global, or account scoped when Account is\nset. Group names the rules a scoped rule overrides; by default it\noverrides less specific rules of the same type.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/rules.RuleScope"
+                        }
+                    ]
+                },
+                "scopeValue": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "Status is active (the default) or shadow.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/rules.RuleStatus"
+                        }
+                    ]
+                },
+                "threshold": {
+                    "type": "number"
+                },
+                "tolerance": {
+                    "description": "Structuring settings: Tolerance is the percentage below Threshold that\ncounts as \"just under\", MinCount how many such transactions raise a flag.",
+                    "type": "number"
+                },
+                "type": {
+                    "$ref": "#/definitions/rules.RuleType"
+                },
+                "version": {
+                    "description": "Version counts the changes to the rule, starting at 1. Every version is\nkept as a RuleVersion snapshot.",
+                    "type": "integer"
+                },
+                "weight": {
+                    "description": "Weight is the number of risk score points (0-100) the rule adds when it\nfails. Empty uses the default for the failing status.",
+                    "type": "number"
+                },
+                "window": {
+                    "description": "Velocity settings: Window is a duration string such as \"1h\" or \"24h\".",
+                    "type": "string"
+                }
+            }
+        },
+        "rules.RuleResult": {
+            "type": "object",
+            "properties": {
+                "children": {
+                    "description": "Children holds how each child of a composite rule evaluated.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/rules.RuleResult"
+                    }
+                },
+                "inputs": {
                     "type": "object",
                     "additionalProperties": {}
                 },
-                "toAcc": {
+                "name": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "ruleId": {
+                    "type": "integer"
+                },
+                "scope": {
+                    "description": "scope the rule matched, e.g. \"customer:C-1\"",
+                    "type": "string"
+                },
+                "score": {
+                    "description": "Score is the rule's contribution to the risk score.",
+                    "type": "number"
+                },
+                "shadow": {
+                    "description": "result of a shadow rule, not part of the decision",
+                    "type": "boolean"
+                },
+                "skipped": {
+                    "type": "boolean"
+                },
+                "status": {
+                    "$ref": "#/definitions/rules.DecisionStatus"
+                },
+                "type": {
+                    "$ref": "#/definitions/rules.RuleType"
+                },
+                "version": {
+                    "description": "rule version evaluated",
+                    "type": "integer"
+                }
+            }
+        },
+        "rules.RuleScope": {
+            "type": "string",
+            "enum": [
+                "global",
+                "segment",
+                "customer",
+                "account"
+            ],
+            "x-enum-varnames": [
+                "ScopeGlobal",
+                "ScopeSegment",
+                "ScopeCustomer",
+                "ScopeAccount"
+            ]
+        },
+        "rules.RuleStatus": {
+            "type": "string",
+            "enum": [
+                "active",
+                "shadow"
+            ],
+            "x-enum-varnames": [
+                "RuleActive",
+                "RuleShadow"
+            ]
+        },
+        "rules.RuleType": {
+            "type": "string",
+            "enum": [
+                "amount_threshold",
+                "sanctions_list",
+                "velocity",
+                "structuring",
+                "name_screening",
+                "expression",
+                "composite"
+            ],
+            "x-enum-varnames": [
+                "RuleTypeAmountThreshold",
+                "RuleTypeSanctionsList",
+                "RuleTypeVelocity",
+                "RuleTypeStructuring",
+                "RuleTypeNameScreening",
+                "RuleTypeExpression",
+                "RuleTypeComposite"
+            ]
+        },
+        "rules.Sanction": {
+            "type": "object",
+            "properties": {
+                "accId": {
+                    "description": "account/customer identifier",
+                    "type": "string"
+                },
+                "aliases": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "country": {
+                    "type": "string"
+                },
+                "externalId": {
+                    "description": "identifier within the source list",
+                    "type": "string"
+                },
+                "listedAt": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "partyType": {
+                    "description": "individual, entity, vessel...",
                     "type": "string"
+                },
+                "program": {
+                    "type": "string"
+                },
+                "source": {
+                    "$ref": "#/definitions/rules.SanctionSource"
                 }
             }
         },
-        "rules.Decision": {
+        "rules.SanctionSource": {
+            "type": "string",
+            "enum": [
+                "manual",
+                "ofac",
+                "un",
+                "eu"
+            ],
+            "x-enum-varnames": [
+                "SanctionSourceManual",
+                "SanctionSourceOFAC",
+                "SanctionSourceUN",
+                "SanctionSourceEU"
+            ]
+        },
+        "rules.TimeWindow": {
             "type": "object",
             "properties": {
-                "approved": {
-                    "type": "boolean"
+                "days": {
+                    "description": "mon..sun; empty is every day",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "end": {
+                    "description": "HH:MM, exclusive",
+                    "type": "string"
+                },
+                "start": {
+                    "description": "HH:MM, inclusive",
+                    "type": "string"
+                },
+                "timezone": {
+                    "type": "string"
+                }
+            }
+        },
+        "rules.VelocityKey": {
+            "type": "string",
+            "enum": [
+                "from_acc",
+                "to_acc",
+                "customer_id"
+            ],
+            "x-enum-varnames": [
+                "VelocityKeyFromAcc",
+                "VelocityKeyToAcc",
+                "VelocityKeyCustomerID"
+            ]
+        },
+        "service.Backtest": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "type": "string"
+                },
+                "mode": {
+                    "$ref": "#/definitions/service.EvaluationMode"
+                },
+                "remove": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "rules": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/rules.Rule"
+                    }
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "service.BacktestComparison": {
+            "type": "object",
+            "properties": {
+                "actual": {
+                    "$ref": "#/definitions/rules.DecisionStatus"
+                },
+                "candidate": {
+                    "$ref": "#/definitions/rules.DecisionStatus"
+                },
+                "count": {
+                    "type": "integer"
+                },
+                "samples": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/service.BacktestSample"
+                    }
+                }
+            }
+        },
+        "service.BacktestReport": {
+            "type": "object",
+            "properties": {
+                "audits": {
+                    "type": "integer"
+                },
+                "changed": {
+                    "type": "integer"
+                },
+                "comparisons": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/service.BacktestComparison"
+                    }
+                },
+                "from": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "service.BacktestSample": {
+            "type": "object",
+            "properties": {
+                "auditId": {
+                    "type": "integer"
                 },
                 "reason": {
                     "type": "string"
+                },
+                "riskScore": {
+                    "type": "number"
+                },
+                "transactionId": {
+                    "type": "string"
                 }
             }
         },
-        "rules.Rule": {
+        "service.BatchResult": {
             "type": "object",
             "properties": {
-                "account": {
+                "decision": {
+                    "$ref": "#/definitions/rules.Decision"
+                },
+                "error": {
                     "type": "string"
                 },
-                "description": {
+                "transactionId": {
                     "type": "string"
+                }
+            }
+        },
+        "service.BulkRejection": {
+            "type": "object",
+            "properties": {
+                "line": {
+                    "type": "integer"
                 },
-                "name": {
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "service.BulkResult": {
+            "type": "object",
+            "properties": {
+                "added": {
+                    "type": "integer"
+                },
+                "duplicated": {
+                    "type": "integer"
+                },
+                "rejected": {
+                    "type": "integer"
+                },
+                "rejections": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/service.BulkRejection"
+                    }
+                }
+            }
+        },
+        "service.ChainReport": {
+            "type": "object",
+            "properties": {
+                "brokenAt": {
+                    "type": "integer"
+                },
+                "checked": {
+                    "type": "integer"
+                },
+                "reason": {
                     "type": "string"
                 },
-                "threshold": {
-                    "type": "number",
-                    "format": "float64"
+                "unchained": {
+                    "type": "integer"
                 },
-                "type": {
-                    "$ref": "#/definitions/rules.RuleType"
+                "valid": {
+                    "type": "boolean"
                 }
             }
         },
-        "rules.RuleType": {
+        "service.EvaluationMode": {
             "type": "string",
             "enum": [
-                "amount_threshold",
-                "sanctions_list"
+                "all",
+                "first_failure"
             ],
             "x-enum-varnames": [
-                "RuleTypeAmountThreshold",
-                "RuleTypeSanctionsList"
+                "ModeAll",
+                "ModeFirstFailure"
             ]
+        },
+        "service.RuleChange": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string"
+                },
+                "from": {},
+                "to": {}
+            }
+        },
+        "service.ShadowComparison": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "live": {
+                    "$ref": "#/definitions/rules.DecisionStatus"
+                },
+                "shadow": {
+                    "$ref": "#/definitions/rules.DecisionStatus"
+                }
+            }
+        },
+        "service.ShadowReport": {
+            "type": "object",
+            "properties": {
+                "audits": {
+                    "description": "audit entries with shadow results",
+                    "type": "integer"
+                },
+                "from": {
+                    "type": "string"
+                },
+                "rules": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/service.ShadowRuleReport"
+                    }
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "service.ShadowRuleReport": {
+            "type": "object",
+            "properties": {
+                "changes": {
+                    "type": "integer"
+                },
+                "comparisons": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/service.ShadowComparison"
+                    }
+                },
+                "evaluated": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "ruleId": {
+                    "type": "integer"
+                },
+                "samples": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "skipped": {
+                    "type": "integer"
+                }
+            }
         }
     }
 }`
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/warleon/ms4-compliance-service/internal/repository/rules"
+	"github.com/warleon/ms4-compliance-service/internal/sanctions"
+	"github.com/warleon/ms4-compliance-service/internal/service"
+)
+
+const commandUsage = `available commands:
+  verify-audit-chain
+  import-sanctions -source ofac -sdn SDN.CSV [-alt ALT.CSV] [-add ADD.CSV]
+  import-sanctions -source un|eu -file LIST.xml
+  backtest -from TIME -to TIME [-rules RULES.json] [-remove 1,2] [-mode all|first_failure]`
+
+// runCommand executes a one-off CLI subcommand instead of starting the server.
+// It returns the process exit code.
+func runCommand(svc *service.ComplianceService, args []string) int {
+	switch args[0] {
+	case "verify-audit-chain":
+		rep, err := svc.VerifyAuditChain(context.Background())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "verify failed: %v\n", err)
+			return 1
+		}
+		printJSON(rep)
+		if !rep.Valid {
+			return 2
+		}
+		return 0
+	case "import-sanctions":
+		if err := importSanctions(svc, args[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "import failed: %v\n", err)
+			return 1
+		}
+		return 0
+	case "backtest":
+		if err := backtest(svc, args[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "backtest failed: %v\n", err)
+			return 1
+		}
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", args[0])
+		fmt.Fprintln(os.Stderr, commandUsage)
+		return 1
+	}
+}
+
+// importSanctions parses an official sanctions list from local files and
+// replaces the entries previously imported from the same source.
+func importSanctions(svc *service.ComplianceService, args []string) error {
+	fs := flag.NewFlagSet("import-sanctions", flag.ContinueOnError)
+	source := fs.String("source", "", "list to import: ofac, un or eu")
+	file := fs.String("file", "", "UN or EU XML file")
+	sdn := fs.String("sdn", "", "OFAC SDN.CSV file")
+	alt := fs.String("alt", "", "OFAC ALT.CSV file (optional)")
+	add := fs.String("add", "", "OFAC ADD.CSV file (optional)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var entries []rules.Sanction
+	var err error
+	switch rules.SanctionSource(*source) {
+	case rules.SanctionSourceOFAC:
+		if *sdn == "" {
+			return fmt.Errorf("-sdn is required for ofac")
+		}
+		var readers [3]io.Reader
+		for i, path := range []string{*sdn, *alt, *add} {
+			if path == "" {
+				continue
+			}
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			readers[i] = f
+		}
+		entries, err = sanctions.ParseOFAC(readers[0], readers[1], readers[2])
+	case rules.SanctionSourceUN, rules.SanctionSourceEU:
+		if *file == "" {
+			return fmt.Errorf("-file is required for %s", *source)
+		}
+		f, ferr := os.Open(*file)
+		if ferr != nil {
+			return ferr
+		}
+		defer f.Close()
+		if *source == string(rules.SanctionSourceUN) {
+			entries, err = sanctions.ParseUN(f)
+		} else {
+			entries, err = sanctions.ParseEU(f)
+		}
+	default:
+		return fmt.Errorf("unknown source %q, use ofac, un or eu", *source)
+	}
+	if err != nil {
+		return err
+	}
+
+	res, err := svc.ImportSanctionList(context.Background(), rules.SanctionSource(*source), entries)
+	if err != nil {
+		return err
+	}
+	printJSON(res)
+	return nil
+}
+
+// backtest replays the audit history between -from and -to through the stored
+// rules with the candidate changes applied and prints the report. -rules is a
+// JSON file with an array of rules, as accepted by the backtest endpoint.
+func backtest(svc *service.ComplianceService, args []string) error {
+	fs := flag.NewFlagSet("backtest", flag.ContinueOnError)
+	from := fs.String("from", "", "start of the time range (RFC3339)")
+	to := fs.String("to", "", "end of the time range (RFC3339, exclusive)")
+	file := fs.String("rules", "", "JSON file with the candidate rules")
+	remove := fs.String("remove", "", "comma-separated IDs of stored rules to leave out")
+	mode := fs.String("mode", "", "evaluation mode: all or first_failure")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	b := service.Backtest{Mode: service.EvaluationMode(*mode)}
+	var err error
+	if b.From, err = time.Parse(time.RFC3339, *from); err != nil {
+		return fmt.Errorf("invalid -from: %w", err)
+	}
+	if b.To, err = time.Parse(time.RFC3339, *to); err != nil {
+		return fmt.Errorf("invalid -to: %w", err)
+	}
+	if *file != "" {
+		data, err := os.ReadFile(*file)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, &b.Rules); err != nil {
+			return fmt.Errorf("invalid -rules file: %w", err)
+		}
+	}
+	for _, id := range strings.Split(*remove, ",") {
+		if id = strings.TrimSpace(id); id == "" {
+			continue
+		}
+		v, err := strconv.ParseUint(id, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid -remove id %q", id)
+		}
+		b.Remove = append(b.Remove, uint(v))
+	}
+
+	rep, err := svc.Backtest(context.Background(), b)
+	if err != nil {
+		return err
+	}
+	printJSON(rep)
+	return nil
+}
+
+func printJSON(v any) {
+	out, _ := json.MarshalIndent(v, "", "  ")
+	fmt.Println(string(out))
+}
@@ -3,6 +3,8 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"time"
 
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
@@ -15,6 +17,36 @@ type Config struct {
 	DBPassword string
 	DBName     string
 	Port       string
+
+	// SanctionsRefreshInterval is how often the in-memory sanctions index is
+	// reloaded from the database.
+	SanctionsRefreshInterval time.Duration
+
+	// EvaluationMode is the default rule evaluation mode: "all" runs every
+	// rule, "first_failure" stops at the first reject.
+	EvaluationMode string
+
+	// RiskReviewScore and RiskRejectScore are the risk score bands: scores at
+	// or above them go to manual review or are rejected. A zero
+	// RiskRejectScore, the default, turns off the reject band.
+	RiskReviewScore float64
+	RiskRejectScore float64
+
+	// IdempotencyRetention is how long a validated transaction ID is
+	// remembered so retries replay its decision. Zero turns replays off.
+	IdempotencyRetention time.Duration
+
+	// BatchMaxSize caps the transactions accepted by one batch validation;
+	// BatchWorkers is how many of them are validated at once.
+	BatchMaxSize int
+	BatchWorkers int
+
+	// JobMaxItems caps the transactions or accounts in one job file.
+	JobMaxItems int
+
+	// CallbackAllowedHosts is a comma separated list of the hosts callbacks
+	// may be sent to; ".example.com" allows its subdomains. Empty allows none.
+	CallbackAllowedHosts string
 }
 
 func Load() (*Config, error) {
@@ -25,6 +57,39 @@ func Load() (*Config, error) {
 		DBPassword: getEnv("DB_PASSWORD", "secret"),
 		DBName:     getEnv("DB_NAME", "compliance"),
 		Port:       getEnv("PORT", "8080"),
+
+		EvaluationMode:       getEnv("EVALUATION_MODE", "all"),
+		CallbackAllowedHosts: os.Getenv("CALLBACK_ALLOWED_HOSTS"),
+	}
+
+	var err error
+	cfg.SanctionsRefreshInterval, err = time.ParseDuration(getEnv("SANCTIONS_REFRESH_INTERVAL", "5m"))
+	if err != nil || cfg.SanctionsRefreshInterval <= 0 {
+		return nil, fmt.Errorf("invalid SANCTIONS_REFRESH_INTERVAL: %q", os.Getenv("SANCTIONS_REFRESH_INTERVAL"))
+	}
+	if cfg.EvaluationMode != "all" && cfg.EvaluationMode != "first_failure" {
+		return nil, fmt.Errorf("invalid EVALUATION_MODE: %q", cfg.EvaluationMode)
+	}
+	if cfg.RiskReviewScore, err = strconv.ParseFloat(getEnv("RISK_REVIEW_SCORE", "40"), 64); err != nil {
+		return nil, fmt.Errorf("invalid RISK_REVIEW_SCORE: %q", os.Getenv("RISK_REVIEW_SCORE"))
+	}
+	if v := os.Getenv("RISK_REJECT_SCORE"); v != "" {
+		if cfg.RiskRejectScore, err = strconv.ParseFloat(v, 64); err != nil {
+			return nil, fmt.Errorf("invalid RISK_REJECT_SCORE: %q", v)
+		}
+	}
+	cfg.IdempotencyRetention, err = time.ParseDuration(getEnv("IDEMPOTENCY_RETENTION", "24h"))
+	if err != nil || cfg.IdempotencyRetention < 0 {
+		return nil, fmt.Errorf("invalid IDEMPOTENCY_RETENTION: %q", os.Getenv("IDEMPOTENCY_RETENTION"))
+	}
+	if cfg.BatchMaxSize, err = strconv.Atoi(getEnv("BATCH_MAX_SIZE", "1000")); err != nil || cfg.BatchMaxSize <= 0 {
+		return nil, fmt.Errorf("invalid BATCH_MAX_SIZE: %q", os.Getenv("BATCH_MAX_SIZE"))
+	}
+	if cfg.BatchWorkers, err = strconv.Atoi(getEnv("BATCH_WORKERS", "8")); err != nil || cfg.BatchWorkers <= 0 {
+		return nil, fmt.Errorf("invalid BATCH_WORKERS: %q", os.Getenv("BATCH_WORKERS"))
+	}
+	if cfg.JobMaxItems, err = strconv.Atoi(getEnv("JOB_MAX_ITEMS", "100000")); err != nil || cfg.JobMaxItems <= 0 {
+		return nil, fmt.Errorf("invalid JOB_MAX_ITEMS: %q", os.Getenv("JOB_MAX_ITEMS"))
 	}
 	return cfg, nil
 }
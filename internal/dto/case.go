@@ -0,0 +1,9 @@
+package dto
+
+// CaseAction is the body of an analyst action on a review case. Assignee is
+// only used when assigning; Comment is optional except when commenting.
+type CaseAction struct {
+	Actor    string `json:"actor" binding:"required"`
+	Assignee string `json:"assignee"`
+	Comment  string `json:"comment"`
+}
@@ -0,0 +1,6 @@
+package dto
+
+// RuleRollback is the body of a rule rollback: the version to restore.
+type RuleRollback struct {
+	Version uint `json:"version" binding:"required"`
+}
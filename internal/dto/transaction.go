@@ -1,11 +1,21 @@
 package dto
 
+import (
+	"time"
+
+	"github.com/warleon/ms4-compliance-service/internal/money"
+)
+
 type Transaction struct {
 	ID         string
 	CustomerID string
 	FromAcc    string
 	ToAcc      string
-	Amount     float64
+	Amount     money.Amount `swaggertype:"number"`
 	Currency   string
 	Metadata   map[string]any
+	// Timestamp is when the transaction took place; rules in effect at that
+	// moment are applied. Empty means now. Omitted when zero so entries
+	// recorded before it existed keep their audit hash.
+	Timestamp time.Time `json:",omitzero"`
 }
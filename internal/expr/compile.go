@@ -0,0 +1,337 @@
+package expr
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/warleon/ms4-compliance-service/internal/dto"
+)
+
+// env is the state of one evaluation.
+type env struct {
+	tx     dto.Transaction
+	budget int
+}
+
+// spend charges n steps against the budget.
+func (e *env) spend(n int) error {
+	e.budget -= n
+	if e.budget < 0 {
+		return ErrCostExceeded
+	}
+	return nil
+}
+
+// strCost is the cost of work proportional to the length of s.
+func strCost(s string) int {
+	return 1 + len(s)/64
+}
+
+// evalFunc evaluates a node to a bool, float64, string or nil (a missing
+// metadata value).
+type evalFunc func(e *env) (any, error)
+
+// compile turns a checked node into a closure.
+func compile(n *node) evalFunc {
+	switch n.op {
+	case opNumber:
+		v := n.num
+		return func(e *env) (any, error) { return v, e.spend(1) }
+	case opString:
+		v := n.str
+		return func(e *env) (any, error) { return v, e.spend(1) }
+	case opBool:
+		v := n.num == 1
+		return func(e *env) (any, error) { return v, e.spend(1) }
+	case opField:
+		return compileField(n.field)
+	case opMetadata:
+		key := n.field
+		return func(e *env) (any, error) {
+			if err := e.spend(1); err != nil {
+				return nil, err
+			}
+			return normalize(e.tx.Metadata[key]), nil
+		}
+	case opCall:
+		return compileCall(n)
+	case "!":
+		arg := compile(n.args[0])
+		return func(e *env) (any, error) {
+			b, err := evalBool(e, arg, n)
+			return !b, err
+		}
+	case "&&", "||":
+		left, right := compile(n.args[0]), compile(n.args[1])
+		short := n.op == "||" // the left value that decides the result
+		return func(e *env) (any, error) {
+			if err := e.spend(1); err != nil {
+				return nil, err
+			}
+			l, err := evalBool(e, left, n.args[0])
+			if err != nil || l == short {
+				return l, err
+			}
+			return evalBool(e, right, n.args[1])
+		}
+	case "in":
+		return compileIn(n)
+	}
+	if len(n.args) == 1 { // unary minus
+		arg := compile(n.args[0])
+		return func(e *env) (any, error) {
+			f, err := evalNumber(e, arg, n.args[0])
+			return -f, err
+		}
+	}
+	return compileBinary(n)
+}
+
+func compileField(name string) evalFunc {
+	var get func(tx *dto.Transaction) any
+	switch name {
+	case "id":
+		get = func(tx *dto.Transaction) any { return tx.ID }
+	case "customerId":
+		get = func(tx *dto.Transaction) any { return tx.CustomerID }
+	case "fromAcc":
+		get = func(tx *dto.Transaction) any { return tx.FromAcc }
+	case "toAcc":
+		get = func(tx *dto.Transaction) any { return tx.ToAcc }
+	case "amount":
+		// the nearest float64, as a literal with the same digits parses to;
+		// see the package doc for where this is exact
+		get = func(tx *dto.Transaction) any { return tx.Amount.Float64() }
+	case "currency":
+		get = func(tx *dto.Transaction) any { return tx.Currency }
+	}
+	return func(e *env) (any, error) { return get(&e.tx), e.spend(1) }
+}
+
+func compileCall(n *node) evalFunc {
+	args := make([]evalFunc, len(n.args))
+	for i, a := range n.args {
+		args[i] = compile(a)
+	}
+	str := func(e *env, i int) (string, error) {
+		v, err := args[i](e)
+		if err != nil {
+			return "", err
+		}
+		s, ok := v.(string)
+		if !ok {
+			return "", typeError(n.args[i], "string", v)
+		}
+		return s, e.spend(strCost(s))
+	}
+	switch n.field {
+	case "lower", "upper", "len":
+		fn := n.field
+		return func(e *env) (any, error) {
+			s, err := str(e, 0)
+			if err != nil {
+				return nil, err
+			}
+			switch fn {
+			case "lower":
+				return strings.ToLower(s), nil
+			case "upper":
+				return strings.ToUpper(s), nil
+			}
+			return float64(len([]rune(s))), nil
+		}
+	case "abs":
+		return func(e *env) (any, error) {
+			f, err := evalNumber(e, args[0], n.args[0])
+			return math.Abs(f), err
+		}
+	}
+	test := map[string]func(s, sub string) bool{
+		"contains":   strings.Contains,
+		"startsWith": strings.HasPrefix,
+		"endsWith":   strings.HasSuffix,
+	}[n.field]
+	return func(e *env) (any, error) {
+		s, err := str(e, 0)
+		if err != nil {
+			return nil, err
+		}
+		sub, err := str(e, 1)
+		if err != nil {
+			return nil, err
+		}
+		return test(s, sub), nil
+	}
+}
+
+func compileIn(n *node) evalFunc {
+	left := compile(n.args[0])
+	items := make([]any, len(n.args[1].args))
+	for i, it := range n.args[1].args {
+		if it.op == opNumber {
+			items[i] = it.num
+		} else {
+			items[i] = it.str
+		}
+	}
+	return func(e *env) (any, error) {
+		v, err := left(e)
+		if err != nil {
+			return nil, err
+		}
+		if err := e.spend(len(items)); err != nil {
+			return nil, err
+		}
+		for _, it := range items {
+			if v == it {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+}
+
+func compileBinary(n *node) evalFunc {
+	left, right := compile(n.args[0]), compile(n.args[1])
+	op := n.op
+	return func(e *env) (any, error) {
+		l, err := left(e)
+		if err != nil {
+			return nil, err
+		}
+		r, err := right(e)
+		if err != nil {
+			return nil, err
+		}
+		if err := e.spend(1); err != nil {
+			return nil, err
+		}
+		switch op {
+		case "==":
+			return l == r, nil
+		case "!=":
+			return l != r, nil
+		}
+		switch l := l.(type) {
+		case float64:
+			rf, ok := r.(float64)
+			if !ok {
+				return nil, typeError(n.args[1], "number", r)
+			}
+			return arith(op, l, rf)
+		case string:
+			rs, ok := r.(string)
+			if !ok {
+				return nil, typeError(n.args[1], "string", r)
+			}
+			if err := e.spend(strCost(l) + strCost(rs)); err != nil {
+				return nil, err
+			}
+			switch op {
+			case "+":
+				return l + rs, nil
+			case "<":
+				return l < rs, nil
+			case "<=":
+				return l <= rs, nil
+			case ">":
+				return l > rs, nil
+			case ">=":
+				return l >= rs, nil
+			}
+			return nil, fmt.Errorf("operator %s is not defined on strings", op)
+		}
+		return nil, typeError(n.args[0], "number or string", l)
+	}
+}
+
+func arith(op string, l, r float64) (any, error) {
+	switch op {
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "/", "%":
+		if r == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		if op == "%" {
+			return math.Mod(l, r), nil
+		}
+		return l / r, nil
+	case "<":
+		return l < r, nil
+	case "<=":
+		return l <= r, nil
+	case ">":
+		return l > r, nil
+	case ">=":
+		return l >= r, nil
+	}
+	return nil, fmt.Errorf("unknown operator %s", op)
+}
+
+func evalBool(e *env, f evalFunc, n *node) (bool, error) {
+	v, err := f(e)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, typeError(n, "bool", v)
+	}
+	return b, nil
+}
+
+func evalNumber(e *env, f evalFunc, n *node) (float64, error) {
+	v, err := f(e)
+	if err != nil {
+		return 0, err
+	}
+	x, ok := v.(float64)
+	if !ok {
+		return 0, typeError(n, "number", v)
+	}
+	return x, nil
+}
+
+// normalize converts a metadata value to one of the types expressions use.
+// Values of other types, such as nested objects, read as missing.
+func normalize(v any) any {
+	switch v := v.(type) {
+	case string, bool, float64:
+		return v
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	}
+	return nil
+}
+
+func typeError(n *node, want string, v any) error {
+	what := "value"
+	if n.op == opMetadata {
+		what = "metadata." + n.field
+	}
+	return fmt.Errorf("position %d: %s is %s, not a %s", n.pos, what, kind(v), want)
+}
+
+func kind(v any) string {
+	switch v.(type) {
+	case nil:
+		return "missing"
+	case bool:
+		return "a bool"
+	case float64:
+		return "a number"
+	case string:
+		return "a string"
+	}
+	return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,157 @@
+// Package expr implements the small expression language used by expression
+// rules. An expression reads transaction fields and metadata and yields a
+// boolean, for example:
+//
+//	amount > 5000 && currency == "USD" && metadata.channel == "web"
+//
+// Expressions have no loops, assignments or access to anything beyond the
+// transaction. They are type checked when compiled, and both their size and
+// the work done to evaluate them are capped.
+//
+// Numbers are float64, amount included. Comparing amount with a number
+// literal of up to 4 decimal places is exact for amounts below 2^53
+// ten-thousandths (about 900 billion), since both sides round to the same
+// float64. Arithmetic on amount is float arithmetic and may round:
+// amount + 0.2 == 0.3 is false for an amount of 0.1.
+package expr
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/warleon/ms4-compliance-service/internal/dto"
+)
+
+// Limits on expression size and evaluation cost.
+const (
+	MaxLength = 2048 // characters of source
+	MaxNodes  = 256  // literals, fields, operators and calls
+	MaxDepth  = 32   // nested parentheses and unary operators
+	MaxCost   = 4096 // evaluation steps; string work costs one step per 64 bytes
+)
+
+// Type is the static type of an expression.
+type Type int
+
+const (
+	TypeAny Type = iota // metadata values, checked when evaluated
+	TypeBool
+	TypeNumber
+	TypeString
+	TypeList
+)
+
+func (t Type) String() string {
+	switch t {
+	case TypeBool:
+		return "bool"
+	case TypeNumber:
+		return "number"
+	case TypeString:
+		return "string"
+	case TypeList:
+		return "list"
+	}
+	return "metadata value"
+}
+
+// is reports whether a value of type t may be used where want is expected.
+func (t Type) is(want Type) bool {
+	return t == want || t == TypeAny
+}
+
+// compatible reports whether t and o may be compared.
+func (t Type) compatible(o Type) bool {
+	return t == o || t == TypeAny || o == TypeAny
+}
+
+// Error is a compile error at a byte offset in the source.
+type Error struct {
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("position %d: %s", e.Pos, e.Msg)
+}
+
+func errorf(pos int, format string, args ...any) error {
+	return &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// ErrCostExceeded is returned when evaluation takes more than MaxCost steps.
+var ErrCostExceeded = errors.New("expression evaluation cost exceeded")
+
+// Program is a compiled expression. It is safe for concurrent use.
+type Program struct {
+	src  string
+	eval evalFunc
+}
+
+// Compile parses and type checks src. The expression must yield a bool.
+func Compile(src string) (*Program, error) {
+	if len(src) > MaxLength {
+		return nil, errorf(MaxLength, "expression is longer than %d characters", MaxLength)
+	}
+	toks, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, errorf(t.pos, "unexpected %s", describe(t))
+	}
+	if !root.typ.is(TypeBool) {
+		return nil, errorf(0, "expression must be a condition, found %s", root.typ)
+	}
+	return &Program{src: src, eval: compile(root)}, nil
+}
+
+// String returns the source of the expression.
+func (p *Program) String() string { return p.src }
+
+// Eval runs the program against tx.
+func (p *Program) Eval(tx dto.Transaction) (bool, error) {
+	e := &env{tx: tx, budget: MaxCost}
+	v, err := p.eval(e)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("expression yielded %s, not a condition", kind(v))
+	}
+	return b, nil
+}
+
+// Cache holds compiled programs by source so rules are compiled once.
+type Cache struct {
+	mu       sync.Mutex
+	programs map[string]*Program
+}
+
+// maxCached bounds the cache; it is cleared when full, since rules change rarely.
+const maxCached = 1024
+
+// Compile returns the cached program for src, compiling it on first use.
+func (c *Cache) Compile(src string) (*Program, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if p, ok := c.programs[src]; ok {
+		return p, nil
+	}
+	p, err := Compile(src)
+	if err != nil {
+		return nil, err
+	}
+	if c.programs == nil || len(c.programs) >= maxCached {
+		c.programs = make(map[string]*Program)
+	}
+	c.programs[src] = p
+	return p, nil
+}
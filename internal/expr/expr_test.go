@@ -0,0 +1,224 @@
+package expr
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/warleon/ms4-compliance-service/internal/dto"
+	"github.com/warleon/ms4-compliance-service/internal/money"
+)
+
+var testTx = dto.Transaction{
+	ID:         "tx-1",
+	CustomerID: "C1",
+	FromAcc:    "ACC-FROM",
+	ToAcc:      "ACC-TO",
+	Amount:     money.MustParse("1000.5"),
+	Currency:   "USD",
+	Metadata: map[string]any{
+		"channel": "web",
+		"score":   float64(7),
+		"count":   3,
+		"vip":     true,
+		"nested":  map[string]any{"a": 1},
+	},
+}
+
+func TestEval(t *testing.T) {
+	for _, tc := range []struct {
+		src  string
+		want bool
+	}{
+		// precedence: * before +, + before comparison, && before ||
+		{`1 + 2 * 3 == 7`, true},
+		{`(1 + 2) * 3 == 9`, true},
+		{`10 - 4 - 3 == 3`, true},
+		{`12 / 3 / 2 == 2`, true},
+		{`7 % 4 + 1 == 4`, true},
+		{`-2 * 3 == -6`, true},
+		{`true || false && false`, true},
+		{`(true || false) && false`, false},
+		{`!false && !!true`, true},
+		{`(1 < 2) == true`, true},
+		// fields, metadata and functions
+		{`amount > 1000 && currency == "USD"`, true},
+		{`amount * 2 == 2001`, true},
+		{`metadata.channel == "web" && metadata["score"] >= 7`, true},
+		{`metadata.count + 1 == 4`, true},
+		{`metadata.vip`, true},
+		{`metadata.missing == metadata.nested`, true},
+		{`currency in ["EUR", "USD"]`, true},
+		{`amount in [1, 2]`, false},
+		{`lower(fromAcc) == "acc-from" && upper("a") == "A"`, true},
+		{`startsWith(toAcc, "ACC") && endsWith(toAcc, "TO") && contains(id, "-")`, true},
+		{`len("héllo") == 5 && abs(-3) == 3`, true},
+		{`customerId + "x" == "C1x" && "a" < "b"`, true},
+		// the right side of && and || is skipped once the left decides
+		{`false && metadata.channel > 1`, false},
+		{`true || 1 / 0 > 1`, true},
+	} {
+		p, err := Compile(tc.src)
+		if err != nil {
+			t.Errorf("Compile(%s): %v", tc.src, err)
+			continue
+		}
+		got, err := p.Eval(testTx)
+		if err != nil || got != tc.want {
+			t.Errorf("%s = %v, %v, want %v", tc.src, got, err, tc.want)
+		}
+	}
+}
+
+func TestAmountIsFloat(t *testing.T) {
+	for _, tc := range []struct {
+		amount, src string
+		want        bool
+	}{
+		// comparisons with literals of up to 4 decimal places are exact
+		{"0.1", `amount == 0.1`, true},
+		{"1000.01", `amount >= 1000.01 && amount < 1000.0101`, true},
+		{"1000.0099", `amount >= 1000.01`, false},
+		{"123456789.1234", `amount == 123456789.1234`, true},
+		{"123456789.1234", `amount > 123456789.1233`, true},
+		{"900719925474.0991", `amount < 900719925474.0992`, true},
+		// past 2^53 ten-thousandths neighbouring amounts round together
+		{"900719925474.0993", `amount == 900719925474.0992`, true},
+		// arithmetic is float arithmetic
+		{"0.1", `amount + 0.2 == 0.3`, false},
+		{"0.1", `abs(amount + 0.2 - 0.3) < 0.0001`, true},
+	} {
+		p, err := Compile(tc.src)
+		if err != nil {
+			t.Fatalf("Compile(%s): %v", tc.src, err)
+		}
+		got, err := p.Eval(dto.Transaction{Amount: money.MustParse(tc.amount)})
+		if err != nil || got != tc.want {
+			t.Errorf("%s with amount %s = %v, %v, want %v", tc.src, tc.amount, got, err, tc.want)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, tc := range []struct {
+		src  string
+		want string
+	}{
+		{`amount`, "must be a condition"},
+		{`amount > "10"`, "cannot combine number and string"},
+		{`currency - 1 > 0`, "cannot combine string and number"},
+		{`true + 1 == 2`, "cannot combine bool and number"},
+		{`!amount`, "operator ! needs a bool"},
+		{`-currency == 1`, "operator - needs a number"},
+		{`amount && true`, "cannot combine number and bool"},
+		{`len(amount) > 1`, "argument 1 of len must be a string"},
+		{`contains(currency) `, "contains takes 2 arguments"},
+		{`balance > 1`, `unknown field "balance"`},
+		{`currency in "USD"`, "must be followed by a list"},
+		{`currency in [1, 2]`, "cannot combine string and list"},
+		{`1 < 2 < 3`, `unexpected "<"`},
+		{`amount in [1, "2"]`, "list mixes"},
+		{`amount > 1)`, `unexpected ")"`},
+		{`(amount > 1`, `expected ")"`},
+		{`metadata > 1`, "expected metadata.key"},
+	} {
+		_, err := Compile(tc.src)
+		var cerr *Error
+		if !errors.As(err, &cerr) || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("Compile(%s): err = %v, want a compile error containing %q", tc.src, err, tc.want)
+		}
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	for _, tc := range []struct {
+		src  string
+		want string
+	}{
+		// metadata is only checked when evaluated
+		{`metadata.channel > 1`, "value is a number, not a string"},
+		{`metadata.score > "1"`, "value is a string, not a number"},
+		{`metadata.score == 7 && metadata.channel`, "metadata.channel is a string, not a bool"},
+		{`metadata.missing > 1`, "missing, not a number or string"},
+		{`-metadata.channel < 0`, "not a number"},
+		{`upper(metadata.count) == "3"`, "not a string"},
+		{`metadata.nested`, "yielded missing, not a condition"},
+		// division by zero
+		{`amount / 0 > 1`, "division by zero"},
+		{`amount % (amount - amount) > 1`, "division by zero"},
+		{`amount / metadata.zero > 1`, "not a number"},
+	} {
+		p, err := Compile(tc.src)
+		if err != nil {
+			t.Errorf("Compile(%s): %v", tc.src, err)
+			continue
+		}
+		if got, err := p.Eval(testTx); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s = %v, %v, want an error containing %q", tc.src, got, err, tc.want)
+		}
+	}
+}
+
+func TestCostLimit(t *testing.T) {
+	tx := testTx
+	tx.Metadata = map[string]any{"note": strings.Repeat("x", 64*MaxCost)}
+	p, err := Compile(`contains(metadata.note, "y")`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Eval(tx); !errors.Is(err, ErrCostExceeded) {
+		t.Fatalf("long string: err = %v, want ErrCostExceeded", err)
+	}
+
+	// an expression near MaxNodes stays within the budget
+	list := `[` + strings.TrimSuffix(strings.Repeat(`"a",`, 20), ",") + `]`
+	terms := make([]string, 10)
+	for i := range terms {
+		terms[i] = `currency in ` + list
+	}
+	if p, err = Compile(strings.Join(terms, " || ")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Eval(testTx); err != nil {
+		t.Fatalf("within budget: %v", err)
+	}
+
+	// the budget is per evaluation
+	tx.Metadata = map[string]any{"note": "short"}
+	if _, err := p.Eval(tx); err != nil {
+		t.Fatalf("second evaluation: %v", err)
+	}
+}
+
+func TestSizeLimits(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"length", `currency == "` + strings.Repeat("a", MaxLength) + `"`, "longer than"},
+		{"depth", strings.Repeat("(", MaxDepth+1) + "true" + strings.Repeat(")", MaxDepth+1), "nested more than"},
+		{"unary depth", strings.Repeat("!", MaxDepth+1) + "true", "nested more than"},
+		{"nodes", strings.TrimSuffix(strings.Repeat("true || ", MaxNodes), " || "), "more than"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := Compile(tc.src); err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("err = %v, want one containing %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestCache(t *testing.T) {
+	var c Cache
+	a, err := c.Compile(`amount > 1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := c.Compile(`amount > 1`); a != b {
+		t.Fatal("cache compiled the same source twice")
+	}
+	if _, err := c.Compile(`amount >`); err == nil {
+		t.Fatal("cache accepted an invalid expression")
+	}
+}
@@ -0,0 +1,379 @@
+package expr
+
+import "fmt"
+
+// node is a parsed expression. typ is filled in by the parser, which checks
+// types as it goes.
+type node struct {
+	pos  int
+	op   string // operator, function name, or one of the kinds below
+	args []*node
+	typ  Type
+
+	num   float64
+	str   string
+	field string // transaction field or metadata key
+}
+
+// Node kinds that are not operators.
+const (
+	opNumber   = "number"
+	opString   = "string"
+	opBool     = "bool"
+	opField    = "field"
+	opMetadata = "metadata"
+	opList     = "list"
+	opCall     = "call"
+)
+
+// fields maps the transaction fields an expression may read to their types.
+var fields = map[string]Type{
+	"id":         TypeString,
+	"customerId": TypeString,
+	"fromAcc":    TypeString,
+	"toAcc":      TypeString,
+	"amount":     TypeNumber,
+	"currency":   TypeString,
+}
+
+// function describes a built-in function.
+type function struct {
+	params []Type
+	result Type
+}
+
+var functions = map[string]function{
+	"lower":      {[]Type{TypeString}, TypeString},
+	"upper":      {[]Type{TypeString}, TypeString},
+	"len":        {[]Type{TypeString}, TypeNumber},
+	"contains":   {[]Type{TypeString, TypeString}, TypeBool},
+	"startsWith": {[]Type{TypeString, TypeString}, TypeBool},
+	"endsWith":   {[]Type{TypeString, TypeString}, TypeBool},
+	"abs":        {[]Type{TypeNumber}, TypeNumber},
+}
+
+type parser struct {
+	toks  []token
+	i     int
+	nodes int
+	depth int
+}
+
+func (p *parser) peek() token { return p.toks[p.i] }
+
+func (p *parser) next() token {
+	t := p.toks[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+// accept consumes the next token if it is the operator op.
+func (p *parser) accept(op string) bool {
+	if t := p.peek(); t.kind == tokOp && t.text == op {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(op string) error {
+	if !p.accept(op) {
+		t := p.peek()
+		return errorf(t.pos, "expected %q, found %s", op, describe(t))
+	}
+	return nil
+}
+
+// newNode counts nodes against MaxNodes.
+func (p *parser) newNode(n *node) (*node, error) {
+	p.nodes++
+	if p.nodes > MaxNodes {
+		return nil, errorf(n.pos, "expression has more than %d terms", MaxNodes)
+	}
+	return n, nil
+}
+
+func (p *parser) parseOr() (*node, error) {
+	return p.parseBinary(p.parseAnd, "||")
+}
+
+func (p *parser) parseAnd() (*node, error) {
+	return p.parseBinary(p.parseCompare, "&&")
+}
+
+func (p *parser) parseAdd() (*node, error) {
+	return p.parseBinary(p.parseMul, "+", "-")
+}
+
+func (p *parser) parseMul() (*node, error) {
+	return p.parseBinary(p.parseUnary, "*", "/", "%")
+}
+
+// parseBinary parses a left-associative chain of the given operators.
+func (p *parser) parseBinary(operand func() (*node, error), ops ...string) (*node, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if t.kind != tokOp || !contains(ops, t.text) {
+			return left, nil
+		}
+		p.next()
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = p.binary(t, left, right); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *parser) parseCompare() (*node, error) {
+	left, err := p.parseAdd()
+	if err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	switch {
+	case t.kind == tokOp && contains([]string{"==", "!=", "<", "<=", ">", ">="}, t.text):
+	case t.kind == tokIdent && t.text == "in":
+	default:
+		return left, nil
+	}
+	p.next()
+	right, err := p.parseAdd()
+	if err != nil {
+		return nil, err
+	}
+	return p.binary(t, left, right)
+}
+
+func (p *parser) parseUnary() (*node, error) {
+	t := p.peek()
+	if t.kind == tokOp && (t.text == "!" || t.text == "-") {
+		p.next()
+		if p.depth++; p.depth > MaxDepth {
+			return nil, errorf(t.pos, "expression is nested more than %d levels deep", MaxDepth)
+		}
+		arg, err := p.parseUnary()
+		p.depth--
+		if err != nil {
+			return nil, err
+		}
+		want := TypeBool
+		if t.text == "-" {
+			want = TypeNumber
+		}
+		if !arg.typ.is(want) {
+			return nil, errorf(t.pos, "operator %s needs a %s, found %s", t.text, want, arg.typ)
+		}
+		return p.newNode(&node{pos: t.pos, op: t.text, args: []*node{arg}, typ: want})
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (*node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNumber:
+		return p.newNode(&node{pos: t.pos, op: opNumber, num: t.num, typ: TypeNumber})
+	case tokString:
+		return p.newNode(&node{pos: t.pos, op: opString, str: t.text, typ: TypeString})
+	case tokIdent:
+		return p.parseIdent(t)
+	case tokOp:
+		switch t.text {
+		case "(":
+			if p.depth++; p.depth > MaxDepth {
+				return nil, errorf(t.pos, "expression is nested more than %d levels deep", MaxDepth)
+			}
+			n, err := p.parseOr()
+			p.depth--
+			if err != nil {
+				return nil, err
+			}
+			return n, p.expect(")")
+		case "[":
+			return p.parseList(t)
+		}
+	}
+	return nil, errorf(t.pos, "unexpected %s", describe(t))
+}
+
+func (p *parser) parseIdent(t token) (*node, error) {
+	switch t.text {
+	case "true", "false":
+		return p.newNode(&node{pos: t.pos, op: opBool, num: b2f(t.text == "true"), typ: TypeBool})
+	case "metadata":
+		key, err := p.parseMetadataKey()
+		if err != nil {
+			return nil, err
+		}
+		return p.newNode(&node{pos: t.pos, op: opMetadata, field: key, typ: TypeAny})
+	}
+	if fn, ok := functions[t.text]; ok && p.accept("(") {
+		return p.parseCall(t, fn)
+	}
+	typ, ok := fields[t.text]
+	if !ok {
+		return nil, errorf(t.pos, "unknown field %q", t.text)
+	}
+	return p.newNode(&node{pos: t.pos, op: opField, field: t.text, typ: typ})
+}
+
+// parseMetadataKey reads the key after "metadata": metadata.key or metadata["key"].
+func (p *parser) parseMetadataKey() (string, error) {
+	if p.accept(".") {
+		k := p.next()
+		if k.kind != tokIdent {
+			return "", errorf(k.pos, "expected a metadata key, found %s", describe(k))
+		}
+		return k.text, nil
+	}
+	if p.accept("[") {
+		k := p.next()
+		if k.kind != tokString {
+			return "", errorf(k.pos, "expected a quoted metadata key, found %s", describe(k))
+		}
+		return k.text, p.expect("]")
+	}
+	t := p.peek()
+	return "", errorf(t.pos, "expected metadata.key or metadata[\"key\"]")
+}
+
+func (p *parser) parseCall(t token, fn function) (*node, error) {
+	n := &node{pos: t.pos, op: opCall, field: t.text, typ: fn.result}
+	for !p.accept(")") {
+		if len(n.args) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		arg, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		n.args = append(n.args, arg)
+	}
+	if len(n.args) != len(fn.params) {
+		return nil, errorf(t.pos, "%s takes %d arguments, found %d", t.text, len(fn.params), len(n.args))
+	}
+	for i, arg := range n.args {
+		if !arg.typ.is(fn.params[i]) {
+			return nil, errorf(arg.pos, "argument %d of %s must be a %s, found %s", i+1, t.text, fn.params[i], arg.typ)
+		}
+	}
+	return p.newNode(n)
+}
+
+// parseList parses a list literal, which may only appear after "in".
+func (p *parser) parseList(t token) (*node, error) {
+	n := &node{pos: t.pos, op: opList, typ: TypeList}
+	for !p.accept("]") {
+		if len(n.args) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		item := p.next()
+		var elem *node
+		switch item.kind {
+		case tokNumber:
+			elem = &node{pos: item.pos, op: opNumber, num: item.num, typ: TypeNumber}
+		case tokString:
+			elem = &node{pos: item.pos, op: opString, str: item.text, typ: TypeString}
+		default:
+			return nil, errorf(item.pos, "list items must be numbers or strings, found %s", describe(item))
+		}
+		if len(n.args) > 0 && elem.typ != n.args[0].typ {
+			return nil, errorf(item.pos, "list mixes %s and %s items", n.args[0].typ, elem.typ)
+		}
+		if _, err := p.newNode(elem); err != nil {
+			return nil, err
+		}
+		n.args = append(n.args, elem)
+	}
+	return p.newNode(n)
+}
+
+// binary type checks and builds a binary operator node.
+func (p *parser) binary(t token, left, right *node) (*node, error) {
+	op := t.text
+	n := &node{pos: t.pos, op: op, args: []*node{left, right}}
+	bad := func() (*node, error) {
+		return nil, errorf(t.pos, "operator %s cannot combine %s and %s", op, left.typ, right.typ)
+	}
+	if left.typ == TypeList || right.typ == TypeList && op != "in" {
+		return nil, errorf(t.pos, "a list can only follow in")
+	}
+	switch op {
+	case "&&", "||":
+		if !left.typ.is(TypeBool) || !right.typ.is(TypeBool) {
+			return bad()
+		}
+		n.typ = TypeBool
+	case "==", "!=":
+		if !left.typ.compatible(right.typ) {
+			return bad()
+		}
+		n.typ = TypeBool
+	case "<", "<=", ">", ">=":
+		if !left.typ.compatible(right.typ) || left.typ == TypeBool || right.typ == TypeBool {
+			return bad()
+		}
+		n.typ = TypeBool
+	case "+":
+		if !left.typ.compatible(right.typ) || left.typ == TypeBool || right.typ == TypeBool {
+			return bad()
+		}
+		n.typ = left.typ
+		if n.typ == TypeAny {
+			n.typ = right.typ
+		}
+	case "-", "*", "/", "%":
+		if !left.typ.is(TypeNumber) || !right.typ.is(TypeNumber) {
+			return bad()
+		}
+		n.typ = TypeNumber
+	case "in":
+		if right.op != opList {
+			return nil, errorf(t.pos, "in must be followed by a list such as [\"USD\", \"EUR\"]")
+		}
+		if len(right.args) > 0 && !left.typ.compatible(right.args[0].typ) {
+			return bad()
+		}
+		n.typ = TypeBool
+	}
+	return p.newNode(n)
+}
+
+func describe(t token) string {
+	switch t.kind {
+	case tokEOF:
+		return "end of expression"
+	case tokString:
+		return fmt.Sprintf("string %q", t.text)
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func b2f(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/warleon/ms4-compliance-service/internal/repository"
+	"github.com/warleon/ms4-compliance-service/internal/repository/rules"
+)
+
+const maxAuditPageSize = 500
+
+// auditOutcomes maps the outcome query parameter to a decision status. The
+// approved, rejected and review spellings predate decision statuses.
+var auditOutcomes = map[string]rules.DecisionStatus{
+	string(rules.StatusApprove):      rules.StatusApprove,
+	string(rules.StatusManualReview): rules.StatusManualReview,
+	string(rules.StatusReject):       rules.StatusReject,
+	"approved":                       rules.StatusApprove,
+	"review":                         rules.StatusManualReview,
+	"rejected":                       rules.StatusReject,
+}
+
+// AuditPage is a page of audit entries. Pass NextCursor as the cursor query
+// parameter to fetch the following page; it is empty on the last page.
+type AuditPage struct {
+	Items      []repository.AuditLog `json:"items"`
+	NextCursor string                `json:"nextCursor,omitempty"`
+}
+
+// ListAudits godoc
+// @Summary List audit entries
+// @Description Retrieves validation decisions recorded in the audit log, newest first, using cursor-based pagination
+// @Tags audits
+// @Accept json
+// @Produce json
+// @Param customerId query string false "Filter by customer ID"
+// @Param transactionId query string false "Filter by transaction ID"
+// @Param outcome query string false "Filter by decision status: approve, manual_review or reject"
+// @Param ruleId query int false "Only entries whose trace includes this rule, also as a child of a composite rule"
+// @Param from query string false "Created at or after (RFC3339)"
+// @Param to query string false "Created before (RFC3339)"
+// @Param cursor query string false "Cursor returned by the previous page"
+// @Param size query int false "Number of results per page (default 50, max 500)"
+// @Success 200 {object} AuditPage
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/audits [get]
+func (h *ComplianceHandler) ListAudits(c *gin.Context) {
+	f := repository.AuditFilter{
+		CustomerID:    c.Query("customerId"),
+		TransactionID: c.Query("transactionId"),
+		Size:          50,
+	}
+	if v := c.Query("outcome"); v != "" {
+		status, ok := auditOutcomes[v]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid outcome"})
+			return
+		}
+		f.Status = status
+	}
+	if s := c.Query("size"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v > 0 {
+			f.Size = min(v, maxAuditPageSize)
+		}
+	}
+	if v := c.Query("ruleId"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid ruleId"})
+			return
+		}
+		f.RuleID = uint(id)
+	}
+	if v := c.Query("cursor"); v != "" {
+		cur, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+			return
+		}
+		f.Cursor = uint(cur)
+	}
+	var err error
+	if f.From, err = timeQuery(c, "from"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from"})
+		return
+	}
+	if f.To, err = timeQuery(c, "to"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to"})
+		return
+	}
+
+	audits, err := h.service.ListAudits(c.Request.Context(), f)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	page := AuditPage{Items: audits}
+	if len(audits) == f.Size {
+		page.NextCursor = strconv.FormatUint(uint64(audits[len(audits)-1].ID), 10)
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// timeQuery parses an optional RFC3339 query parameter.
+func timeQuery(c *gin.Context, name string) (*time.Time, error) {
+	v := c.Query(name)
+	if v == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// GetAudit godoc
+// @Summary Get audit entry by ID
+// @Description Retrieves an audit entry with its transaction, decision and full per-rule trace
+// @Tags audits
+// @Accept json
+// @Produce json
+// @Param id path int true "Audit ID"
+// @Success 200 {object} repository.AuditLog
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/audits/{id} [get]
+func (h *ComplianceHandler) GetAudit(c *gin.Context) {
+	idStr := c.Param("id")
+	id64, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	a, err := h.service.GetAudit(c.Request.Context(), uint(id64))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "audit not found"})
+		return
+	}
+	c.JSON(http.StatusOK, a)
+}
+
+// VerifyAuditChain godoc
+// @Summary Verify the audit hash chain
+// @Description Recomputes the hash of every audit entry and reports the first entry whose content or link to the previous entry was changed
+// @Tags audits
+// @Accept json
+// @Produce json
+// @Success 200 {object} service.ChainReport
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/audits/verify [get]
+func (h *ComplianceHandler) VerifyAuditChain(c *gin.Context) {
+	rep, err := h.service.VerifyAuditChain(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, rep)
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/warleon/ms4-compliance-service/internal/service"
+)
+
+// Backtest godoc
+// @Summary Backtest a candidate rule set
+// @Description Replays the transactions audited between from and to through the stored rules with the candidate changes applied, and compares the outcomes with the recorded decisions. Rules with the ID of a stored rule replace it, rules without an ID are added, and remove lists stored rules to leave out. Each transaction sees the rules in effect at its time and the transaction history recorded before its audit entry. Sanctions lists are the current ones, so sanctions results are approximate. Nothing is written.
+// @Tags rules
+// @Accept json
+// @Produce json
+// @Param backtest body service.Backtest true "Candidate rules and time range"
+// @Success 200 {object} service.BacktestReport
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/reports/backtest [post]
+func (h *ComplianceHandler) Backtest(c *gin.Context) {
+	var b service.Backtest
+	if err := c.ShouldBindJSON(&b); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	rep, err := h.service.Backtest(c.Request.Context(), b)
+	switch {
+	case errors.Is(err, service.ErrInvalidBacktest), errors.Is(err, service.ErrInvalidRule),
+		errors.Is(err, service.ErrInvalidMode):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrRuleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, rep)
+	}
+}
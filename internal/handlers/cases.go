@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/warleon/ms4-compliance-service/internal/dto"
+	"github.com/warleon/ms4-compliance-service/internal/repository"
+	"github.com/warleon/ms4-compliance-service/internal/service"
+)
+
+// ListCases godoc
+// @Summary List review cases
+// @Description Retrieves a paginated list of manual review cases, oldest first
+// @Tags cases
+// @Accept json
+// @Produce json
+// @Param status query string false "Filter by status: open, assigned, approved or rejected"
+// @Param assignee query string false "Filter by assigned analyst"
+// @Param transactionId query string false "Filter by transaction ID"
+// @Param size query int false "Number of results per page (default 50)"
+// @Param offset query int false "Offset for pagination (default 0)"
+// @Success 200 {array} repository.Case
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/cases [get]
+func (h *ComplianceHandler) ListCases(c *gin.Context) {
+	f := repository.CaseFilter{
+		Status:        repository.CaseStatus(c.Query("status")),
+		Assignee:      c.Query("assignee"),
+		TransactionID: c.Query("transactionId"),
+		Size:          50,
+	}
+	if s := c.Query("size"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil {
+			f.Size = v
+		}
+	}
+	if o := c.Query("offset"); o != "" {
+		if v, err := strconv.Atoi(o); err == nil {
+			f.Offset = v
+		}
+	}
+	cs, err := h.service.ListCases(c.Request.Context(), f)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, cs)
+}
+
+// GetCase godoc
+// @Summary Get review case by ID
+// @Description Retrieves a review case with its event history. Poll it to learn the final outcome.
+// @Tags cases
+// @Accept json
+// @Produce json
+// @Param id path int true "Case ID"
+// @Success 200 {object} repository.Case
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/cases/{id} [get]
+func (h *ComplianceHandler) GetCase(c *gin.Context) {
+	id, ok := caseID(c)
+	if !ok {
+		return
+	}
+	cs, err := h.service.GetCase(c.Request.Context(), id)
+	if err != nil {
+		caseError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, cs)
+}
+
+// AssignCase godoc
+// @Summary Assign a review case
+// @Description Assigns an open case to an analyst, or reassigns it
+// @Tags cases
+// @Accept json
+// @Produce json
+// @Param id path int true "Case ID"
+// @Param action body dto.CaseAction true "Actor and assignee"
+// @Success 200 {object} repository.Case
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/cases/{id}/assign [post]
+func (h *ComplianceHandler) AssignCase(c *gin.Context) {
+	h.caseAction(c, h.service.AssignCase)
+}
+
+// CommentCase godoc
+// @Summary Comment on a review case
+// @Description Adds an analyst comment to an open case
+// @Tags cases
+// @Accept json
+// @Produce json
+// @Param id path int true "Case ID"
+// @Param action body dto.CaseAction true "Actor and comment"
+// @Success 200 {object} repository.Case
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/cases/{id}/comments [post]
+func (h *ComplianceHandler) CommentCase(c *gin.Context) {
+	h.caseAction(c, h.service.CommentCase)
+}
+
+// ApproveCase godoc
+// @Summary Approve a review case
+// @Description Closes a case by approving the transaction and notifies the callback URL, if any
+// @Tags cases
+// @Accept json
+// @Produce json
+// @Param id path int true "Case ID"
+// @Param action body dto.CaseAction true "Actor and optional comment"
+// @Success 200 {object} repository.Case
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/cases/{id}/approve [post]
+func (h *ComplianceHandler) ApproveCase(c *gin.Context) {
+	h.caseAction(c, h.resolver(true))
+}
+
+// RejectCase godoc
+// @Summary Reject a review case
+// @Description Closes a case by rejecting the transaction and notifies the callback URL, if any
+// @Tags cases
+// @Accept json
+// @Produce json
+// @Param id path int true "Case ID"
+// @Param action body dto.CaseAction true "Actor and optional comment"
+// @Success 200 {object} repository.Case
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/cases/{id}/reject [post]
+func (h *ComplianceHandler) RejectCase(c *gin.Context) {
+	h.caseAction(c, h.resolver(false))
+}
+
+type caseActionFunc func(ctx context.Context, id uint, in dto.CaseAction) (*repository.Case, error)
+
+func (h *ComplianceHandler) resolver(approve bool) caseActionFunc {
+	return func(ctx context.Context, id uint, in dto.CaseAction) (*repository.Case, error) {
+		return h.service.ResolveCase(ctx, id, approve, in)
+	}
+}
+
+// caseAction binds the case ID and action body, runs fn and writes the updated case.
+func (h *ComplianceHandler) caseAction(c *gin.Context, fn caseActionFunc) {
+	id, ok := caseID(c)
+	if !ok {
+		return
+	}
+	var in dto.CaseAction
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	cs, err := fn(c.Request.Context(), id, in)
+	if err != nil {
+		caseError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, cs)
+}
+
+func caseID(c *gin.Context) (uint, bool) {
+	id64, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return 0, false
+	}
+	return uint(id64), true
+}
+
+func caseError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrCaseNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrCaseClosed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrCaseAction):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -1,15 +1,20 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/warleon/ms4-compliance-service/internal/dto"
+	"github.com/warleon/ms4-compliance-service/internal/repository"
 	"github.com/warleon/ms4-compliance-service/internal/repository/rules"
 	"github.com/warleon/ms4-compliance-service/internal/service"
 )
 
+// ReplayedHeader marks a validation response that returns a stored decision.
+const ReplayedHeader = "Idempotent-Replayed"
+
 type ComplianceHandler struct {
 	service *service.ComplianceService
 }
@@ -20,13 +25,16 @@ func NewComplianceHandler(s *service.ComplianceService) *ComplianceHandler {
 
 // ValidateTransaction godoc
 // @Summary Validate a transaction
-// @Description Validates a transaction against compliance rules and returns a decision
+// @Description Validates a transaction against compliance rules and returns a decision. Status is approve, manual_review or reject; Approved is true only for approve. Results lists the outcome and risk score contribution of every rule evaluated; RiskScore is their 0-100 total. Repeating a transaction ID within the retention period returns the stored decision with the Idempotent-Replayed header set; a different payload under the same ID is a conflict.
 // @Tags compliance
 // @Accept json
 // @Produce json
 // @Param transaction body dto.Transaction true "Transaction data"
+// @Param mode query string false "Evaluation mode: all (every rule) or first_failure (stop at the first reject); defaults to the service setting"
 // @Success 200 {object} rules.Decision
+// @Header 200 {string} Idempotent-Replayed "true when the decision was stored by an earlier request"
 // @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /api/v1/validateTransaction [post]
 func (h *ComplianceHandler) ValidateTransaction(c *gin.Context) {
@@ -35,14 +43,69 @@ func (h *ComplianceHandler) ValidateTransaction(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	dec, err := h.service.ValidateTransaction(c.Request.Context(), tx)
+	mode := service.EvaluationMode(c.Query("mode"))
+	dec, err := h.service.ValidateTransaction(c.Request.Context(), tx, mode)
+	if errors.Is(err, service.ErrInvalidMode) || errors.Is(err, service.ErrInvalidAmount) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, service.ErrIdempotencyConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	if dec.Replayed {
+		c.Header(ReplayedHeader, "true")
+	}
 	c.JSON(http.StatusOK, dec)
 }
 
+// ValidateTransactions godoc
+// @Summary Validate a batch of transactions
+// @Description Validates up to the configured batch size of transactions, sent as a JSON array or as NDJSON (one transaction per line). Transactions are validated concurrently, each with its own decision and audit entry, and the results come back in input order. A transaction that cannot be validated gets an error in its result instead of a decision.
+// @Tags compliance
+// @Accept json,application/x-ndjson
+// @Produce json
+// @Param transactions body []dto.Transaction true "Transactions"
+// @Param mode query string false "Evaluation mode: all (every rule) or first_failure (stop at the first reject); defaults to the service setting"
+// @Success 200 {array} service.BatchResult
+// @Failure 400 {object} map[string]string
+// @Failure 413 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/validateTransactions [post]
+func (h *ComplianceHandler) ValidateTransactions(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBulkUploadBytes)
+	txs, err := service.ReadTransactions(c.Request.Body, h.service.BatchMaxSize)
+	if err != nil {
+		batchError(c, err)
+		return
+	}
+	mode := service.EvaluationMode(c.Query("mode"))
+	res, err := h.service.ValidateTransactions(c.Request.Context(), txs, mode)
+	if err != nil {
+		batchError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// batchError writes the response for a batch that could not be read or run.
+func batchError(c *gin.Context, err error) {
+	var tooBig *http.MaxBytesError
+	switch {
+	case errors.Is(err, service.ErrBatchTooLarge), errors.As(err, &tooBig):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidBatch), errors.Is(err, service.ErrEmptyBatch),
+		errors.Is(err, service.ErrInvalidMode):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
 // CreateRule godoc
 // @Summary Create a new rule
 // @Description Creates a compliance rule in the system
@@ -61,6 +124,10 @@ func (h *ComplianceHandler) CreateRule(c *gin.Context) {
 		return
 	}
 	if err := h.service.CreateRule(c.Request.Context(), &r); err != nil {
+		if errors.Is(err, service.ErrInvalidRule) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -75,23 +142,40 @@ func (h *ComplianceHandler) CreateRule(c *gin.Context) {
 // @Produce json
 // @Param size query int false "Number of results per page (default 50)"
 // @Param offset query int false "Offset for pagination (default 0)"
+// @Param period query string false "Filter by effective dates: upcoming, active or expired"
+// @Param at query string false "Moment the period is judged at (RFC3339, default now)"
 // @Success 200 {array} rules.Rule
+// @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /api/v1/rules [get]
 func (h *ComplianceHandler) ListRules(c *gin.Context) {
-	size := 50
-	offset := 0
+	f := repository.RuleFilter{Size: 50}
 	if s := c.Query("size"); s != "" {
 		if v, err := strconv.Atoi(s); err == nil {
-			size = v
+			f.Size = v
 		}
 	}
 	if o := c.Query("offset"); o != "" {
 		if v, err := strconv.Atoi(o); err == nil {
-			offset = v
+			f.Offset = v
 		}
 	}
-	rs, err := h.service.ListRules(c.Request.Context(), size, offset)
+	if p := c.Query("period"); p != "" {
+		f.Period = rules.RulePeriod(p)
+		if !f.Period.Valid() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid period"})
+			return
+		}
+	}
+	at, err := timeQuery(c, "at")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid at"})
+		return
+	}
+	if at != nil {
+		f.At = *at
+	}
+	rs, err := h.service.ListRules(c.Request.Context(), f)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -127,7 +211,7 @@ func (h *ComplianceHandler) GetRule(c *gin.Context) {
 
 // UpdateRule godoc
 // @Summary Update an existing rule
-// @Description Updates a compliance rule by ID
+// @Description Replaces a compliance rule by ID with the rule sent and records the result as a new version. Fields left out of the body are cleared.
 // @Tags rules
 // @Accept json
 // @Produce json
@@ -135,6 +219,7 @@ func (h *ComplianceHandler) GetRule(c *gin.Context) {
 // @Param rule body rules.Rule true "Updated rule data"
 // @Success 200 {object} rules.Rule
 // @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /api/v1/rules/{id} [put]
 func (h *ComplianceHandler) UpdateRule(c *gin.Context) {
@@ -151,6 +236,14 @@ func (h *ComplianceHandler) UpdateRule(c *gin.Context) {
 	}
 	r.ID = uint(id64)
 	if err := h.service.UpdateRule(c.Request.Context(), &r); err != nil {
+		if errors.Is(err, service.ErrInvalidRule) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrRuleNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -159,13 +252,15 @@ func (h *ComplianceHandler) UpdateRule(c *gin.Context) {
 
 // DeleteRule godoc
 // @Summary Delete a rule
-// @Description Deletes a compliance rule by ID
+// @Description Deletes a compliance rule by ID and records the deletion as a final version. A rule used by a composite rule cannot be deleted (409).
 // @Tags rules
 // @Accept json
 // @Produce json
 // @Param id path int true "Rule ID"
 // @Success 204 "No Content"
 // @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /api/v1/rules/{id} [delete]
 func (h *ComplianceHandler) DeleteRule(c *gin.Context) {
@@ -176,6 +271,14 @@ func (h *ComplianceHandler) DeleteRule(c *gin.Context) {
 		return
 	}
 	if err := h.service.DeleteRule(c.Request.Context(), uint(id64)); err != nil {
+		if errors.Is(err, service.ErrRuleInUse) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrRuleNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
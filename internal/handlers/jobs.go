@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/warleon/ms4-compliance-service/internal/repository"
+	"github.com/warleon/ms4-compliance-service/internal/service"
+)
+
+// maxJobUploadBytes bounds the size of a job file.
+const maxJobUploadBytes = 256 << 20
+
+// jobResultsPage is how many results are read at a time while streaming.
+const jobResultsPage = 500
+
+// CreateJob godoc
+// @Summary Submit a validation job
+// @Description Queues a file for background screening and returns the job. kind=transactions validates a JSON array or NDJSON of transactions, each audited like a single validation. kind=accounts checks account identifiers, with an optional party name, against the sanctions lists; the file is CSV (accId,name) or JSONL ({"accId": "...", "name": "..."} per line). The body may be the raw file or a multipart form with a "file" field. When callbackUrl is set, the finished job is POSTed to it; its host must be listed in CALLBACK_ALLOWED_HOSTS.
+// @Tags jobs
+// @Accept json,application/x-ndjson,text/csv,multipart/form-data
+// @Produce json
+// @Param kind query string true "transactions or accounts"
+// @Param format query string false "Accounts file format: csv or jsonl"
+// @Param mode query string false "Evaluation mode for transactions: all or first_failure"
+// @Param callbackUrl query string false "URL notified when the job completes, on an allowed host"
+// @Param file formData file false "File to upload (multipart)"
+// @Success 202 {object} repository.Job
+// @Failure 400 {object} map[string]string
+// @Failure 413 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/jobs [post]
+func (h *ComplianceHandler) CreateJob(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxJobUploadBytes)
+
+	up := service.JobUpload{
+		Kind:        repository.JobKind(c.Query("kind")),
+		Format:      c.Query("format"),
+		Mode:        service.EvaluationMode(c.Query("mode")),
+		CallbackURL: c.Query("callbackUrl"),
+	}
+	var body io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		fh, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing file"})
+			return
+		}
+		f, err := fh.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		defer f.Close()
+		body = f
+		if up.Format == "" {
+			up.Format = uploadFormat(filepath.Ext(fh.Filename), fh.Header.Get("Content-Type"))
+		}
+	} else if up.Format == "" {
+		up.Format = uploadFormat("", c.ContentType())
+	}
+
+	job, err := h.service.SubmitJob(c.Request.Context(), up, body)
+	if err != nil {
+		var tooBig *http.MaxBytesError
+		switch {
+		case errors.Is(err, service.ErrBatchTooLarge), errors.As(err, &tooBig):
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrInvalidJob), errors.Is(err, service.ErrInvalidBatch),
+			errors.Is(err, service.ErrEmptyBatch), errors.Is(err, service.ErrInvalidMode):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusAccepted, job)
+}
+
+// GetJob godoc
+// @Summary Get a validation job
+// @Description Reports a job's status (queued, running or completed) and progress: total items, items processed and items that could not be screened
+// @Tags jobs
+// @Accept json
+// @Produce json
+// @Param id path int true "Job ID"
+// @Success 200 {object} repository.Job
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/jobs/{id} [get]
+func (h *ComplianceHandler) GetJob(c *gin.Context) {
+	id64, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	job, err := h.service.GetJob(c.Request.Context(), uint(id64))
+	if errors.Is(err, service.ErrJobNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// GetJobResults godoc
+// @Summary Download validation job results
+// @Description Streams the results screened so far as NDJSON, one line per item in file order: a batch validation result for transactions, or an account screening result for accounts
+// @Tags jobs
+// @Produce application/x-ndjson
+// @Param id path int true "Job ID"
+// @Success 200 {string} string "One JSON result per line"
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/jobs/{id}/results [get]
+func (h *ComplianceHandler) GetJobResults(c *gin.Context) {
+	id64, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	ctx := c.Request.Context()
+	job, err := h.service.GetJob(ctx, uint(id64))
+	if errors.Is(err, service.ErrJobNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Status(http.StatusOK)
+	for after := 0; ; {
+		items, err := h.service.JobResults(ctx, job.ID, after, jobResultsPage)
+		if err != nil {
+			// the status line is already sent; cut the stream short
+			c.Error(err)
+			return
+		}
+		for _, it := range items {
+			c.Writer.Write(it.Result)
+			c.Writer.Write([]byte("\n"))
+			after = it.Seq
+		}
+		if len(items) < jobResultsPage {
+			return
+		}
+	}
+}
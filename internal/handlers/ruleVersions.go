@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/warleon/ms4-compliance-service/internal/dto"
+	"github.com/warleon/ms4-compliance-service/internal/service"
+)
+
+// ListRuleVersions godoc
+// @Summary List rule versions
+// @Description Retrieves every version of a rule, newest first. Each update, rollback or delete adds a version; the one added on delete is marked deleted.
+// @Tags rules
+// @Accept json
+// @Produce json
+// @Param id path int true "Rule ID"
+// @Success 200 {array} repository.RuleVersion
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/rules/{id}/versions [get]
+func (h *ComplianceHandler) ListRuleVersions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	vs, err := h.service.ListRuleVersions(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, vs)
+}
+
+// DiffRuleVersions godoc
+// @Summary Diff two rule versions
+// @Description Lists the fields that changed between two versions of a rule
+// @Tags rules
+// @Accept json
+// @Produce json
+// @Param id path int true "Rule ID"
+// @Param from query int true "Older version"
+// @Param to query int true "Newer version"
+// @Success 200 {array} service.RuleChange
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/rules/{id}/versions/diff [get]
+func (h *ComplianceHandler) DiffRuleVersions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	from, err := strconv.ParseUint(c.Query("from"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from"})
+		return
+	}
+	to, err := strconv.ParseUint(c.Query("to"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to"})
+		return
+	}
+	changes, err := h.service.DiffRuleVersions(c.Request.Context(), uint(id), uint(from), uint(to))
+	if errors.Is(err, service.ErrRuleVersionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, changes)
+}
+
+// RollbackRule godoc
+// @Summary Roll back a rule
+// @Description Restores the settings of an earlier version of a rule. The rollback is recorded as a new version.
+// @Tags rules
+// @Accept json
+// @Produce json
+// @Param id path int true "Rule ID"
+// @Param rollback body dto.RuleRollback true "Version to restore"
+// @Success 200 {object} rules.Rule
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/rules/{id}/rollback [post]
+func (h *ComplianceHandler) RollbackRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var in dto.RuleRollback
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	r, err := h.service.RollbackRule(c.Request.Context(), uint(id), in.Version)
+	switch {
+	case errors.Is(err, service.ErrRuleNotFound), errors.Is(err, service.ErrRuleVersionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidRule):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, r)
+	}
+}
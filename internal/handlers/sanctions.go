@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/warleon/ms4-compliance-service/internal/repository/rules"
+	"github.com/warleon/ms4-compliance-service/internal/service"
+)
+
+// maxBulkUploadBytes bounds the size of a bulk sanctions upload.
+const maxBulkUploadBytes = 32 << 20
+
+// CreateSanction godoc
+// @Summary Add a sanctioned account
+// @Description Adds an account identifier to the sanctions list
+// @Tags sanctions
+// @Accept json
+// @Produce json
+// @Param sanction body rules.Sanction true "Sanction data"
+// @Success 201 {object} rules.Sanction
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/sanctions [post]
+func (h *ComplianceHandler) CreateSanction(c *gin.Context) {
+	var s rules.Sanction
+	if err := c.ShouldBindJSON(&s); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.service.CreateSanction(c.Request.Context(), &s); err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidSanction):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrDuplicateSanction):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, s)
+}
+
+// ListSanctions godoc
+// @Summary List sanctioned accounts
+// @Description Retrieves a paginated list of sanctions entries
+// @Tags sanctions
+// @Accept json
+// @Produce json
+// @Param size query int false "Number of results per page (default 50)"
+// @Param offset query int false "Offset for pagination (default 0)"
+// @Success 200 {array} rules.Sanction
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/sanctions [get]
+func (h *ComplianceHandler) ListSanctions(c *gin.Context) {
+	size := 50
+	offset := 0
+	if s := c.Query("size"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil {
+			size = v
+		}
+	}
+	if o := c.Query("offset"); o != "" {
+		if v, err := strconv.Atoi(o); err == nil {
+			offset = v
+		}
+	}
+	ss, err := h.service.ListSanctions(c.Request.Context(), size, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, ss)
+}
+
+// GetSanction godoc
+// @Summary Get sanctions entry by ID
+// @Description Retrieves a specific sanctions entry by its ID
+// @Tags sanctions
+// @Accept json
+// @Produce json
+// @Param id path int true "Sanction ID"
+// @Success 200 {object} rules.Sanction
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/sanctions/{id} [get]
+func (h *ComplianceHandler) GetSanction(c *gin.Context) {
+	idStr := c.Param("id")
+	id64, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	s, err := h.service.GetSanction(c.Request.Context(), uint(id64))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "sanction not found"})
+		return
+	}
+	c.JSON(http.StatusOK, s)
+}
+
+// DeleteSanction godoc
+// @Summary Delete a sanctions entry
+// @Description Removes an account from the sanctions list by entry ID
+// @Tags sanctions
+// @Accept json
+// @Produce json
+// @Param id path int true "Sanction ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/sanctions/{id} [delete]
+func (h *ComplianceHandler) DeleteSanction(c *gin.Context) {
+	idStr := c.Param("id")
+	id64, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	if err := h.service.DeleteSanction(c.Request.Context(), uint(id64)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// BulkUploadSanctions godoc
+// @Summary Bulk upload sanctioned accounts
+// @Description Imports account identifiers from a CSV (first column) or JSONL ({"accId": "..."} per line) upload in a single transaction. The body may be the raw file or a multipart form with a "file" field. The format is taken from the format parameter, the file extension or the Content-Type.
+// @Tags sanctions
+// @Accept text/csv,application/x-ndjson,multipart/form-data
+// @Produce json
+// @Param format query string false "csv or jsonl"
+// @Param file formData file false "File to upload (multipart)"
+// @Success 200 {object} service.BulkResult
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/sanctions/bulk [post]
+func (h *ComplianceHandler) BulkUploadSanctions(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBulkUploadBytes)
+
+	format := c.Query("format")
+	var body io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		fh, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing file"})
+			return
+		}
+		f, err := fh.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		defer f.Close()
+		body = f
+		if format == "" {
+			format = uploadFormat(filepath.Ext(fh.Filename), fh.Header.Get("Content-Type"))
+		}
+	} else if format == "" {
+		format = uploadFormat("", c.ContentType())
+	}
+	if format != service.SanctionFormatCSV && format != service.SanctionFormatJSONL {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown format, use csv or jsonl"})
+		return
+	}
+
+	res, err := h.service.ImportSanctions(c.Request.Context(), format, body)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// uploadFormat guesses a bulk upload format from a file extension or content type.
+func uploadFormat(ext, contentType string) string {
+	switch strings.ToLower(ext) {
+	case ".csv":
+		return service.SanctionFormatCSV
+	case ".jsonl", ".ndjson":
+		return service.SanctionFormatJSONL
+	}
+	switch contentType {
+	case "text/csv":
+		return service.SanctionFormatCSV
+	case "application/x-ndjson", "application/jsonl", "application/x-jsonlines":
+		return service.SanctionFormatJSONL
+	}
+	return ""
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ShadowReport godoc
+// @Summary Compare shadow rules with live decisions
+// @Description Summarizes shadow rule results recorded in the audit log over a time range: outcome pairs against the live decision, how often a shadow rule would have made the decision stricter, and sample audit IDs
+// @Tags rules
+// @Accept json
+// @Produce json
+// @Param from query string false "Created at or after (RFC3339, default 24h before to)"
+// @Param to query string false "Created before (RFC3339, default now)"
+// @Param ruleId query int false "Only this shadow rule"
+// @Success 200 {object} service.ShadowReport
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/reports/shadow [get]
+func (h *ComplianceHandler) ShadowReport(c *gin.Context) {
+	to, err := timeQuery(c, "to")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to"})
+		return
+	}
+	if to == nil {
+		now := time.Now()
+		to = &now
+	}
+	from, err := timeQuery(c, "from")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from"})
+		return
+	}
+	if from == nil {
+		t := to.Add(-24 * time.Hour)
+		from = &t
+	}
+	if !from.Before(*to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to"})
+		return
+	}
+	var ruleID uint64
+	if v := c.Query("ruleId"); v != "" {
+		if ruleID, err = strconv.ParseUint(v, 10, 64); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid ruleId"})
+			return
+		}
+	}
+	report, err := h.service.ShadowReport(c.Request.Context(), *from, *to, uint(ruleID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
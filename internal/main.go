@@ -1,9 +1,12 @@
 package main
 
 import (
+	"expvar"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -12,6 +15,7 @@ import (
 	"github.com/warleon/ms4-compliance-service/internal/config"
 	"github.com/warleon/ms4-compliance-service/internal/handlers"
 	"github.com/warleon/ms4-compliance-service/internal/middleware"
+	"github.com/warleon/ms4-compliance-service/internal/notify"
 	"github.com/warleon/ms4-compliance-service/internal/repository"
 	"github.com/warleon/ms4-compliance-service/internal/repository/rules"
 	"github.com/warleon/ms4-compliance-service/internal/service"
@@ -53,8 +57,30 @@ func main() {
 		db.AutoMigrate(r)
 	}
 
-	repo := repository.NewMySQLRepository(db)
+	repo, sanctionsIndex, err := repository.NewIndexedRepository(repository.NewMySQLRepository(db))
+	if err != nil {
+		logrus.WithError(err).Warn("failed to load sanctions index, falling back to database lookups")
+	}
 	compService := service.NewComplianceService(repo)
+	compService.DefaultMode = service.EvaluationMode(cfg.EvaluationMode)
+	compService.IdempotencyRetention = cfg.IdempotencyRetention
+	compService.BatchMaxSize = cfg.BatchMaxSize
+	compService.BatchWorkers = cfg.BatchWorkers
+	compService.JobMaxItems = cfg.JobMaxItems
+	compService.CallbackHosts = notify.ParseAllowlist(cfg.CallbackAllowedHosts)
+	compService.Bands = rules.RiskBands{Review: cfg.RiskReviewScore, Reject: cfg.RiskRejectScore}
+	if err := compService.Bands.Check(); err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(compService, os.Args[1:]))
+	}
+
+	go sanctionsIndex.Run(cfg.SanctionsRefreshInterval)
+	go compService.PurgeIdempotencyKeys(time.Hour)
+	go compService.RunJobs(time.Minute)
+
 	handler := handlers.NewComplianceHandler(compService)
 
 	r := gin.New()
@@ -68,14 +94,42 @@ func main() {
 	api := r.Group("/api/v1")
 	{
 		api.POST("/validateTransaction", handler.ValidateTransaction)
+		api.POST("/validateTransactions", handler.ValidateTransactions)
+		api.POST("/jobs", handler.CreateJob)
+		api.GET("/jobs/:id", handler.GetJob)
+		api.GET("/jobs/:id/results", handler.GetJobResults)
 		api.POST("/rules", handler.CreateRule)
 		api.GET("/rules/:id", handler.GetRule)
 		api.GET("/rules", handler.ListRules)
 		api.PUT("/rules/:id", handler.UpdateRule)
 		api.DELETE("/rules/:id", handler.DeleteRule)
+		api.GET("/rules/:id/versions", handler.ListRuleVersions)
+		api.GET("/rules/:id/versions/diff", handler.DiffRuleVersions)
+		api.POST("/rules/:id/rollback", handler.RollbackRule)
+
+		api.POST("/sanctions", handler.CreateSanction)
+		api.POST("/sanctions/bulk", handler.BulkUploadSanctions)
+		api.GET("/sanctions/:id", handler.GetSanction)
+		api.GET("/sanctions", handler.ListSanctions)
+		api.DELETE("/sanctions/:id", handler.DeleteSanction)
+
+		api.GET("/cases", handler.ListCases)
+		api.GET("/cases/:id", handler.GetCase)
+		api.POST("/cases/:id/assign", handler.AssignCase)
+		api.POST("/cases/:id/comments", handler.CommentCase)
+		api.POST("/cases/:id/approve", handler.ApproveCase)
+		api.POST("/cases/:id/reject", handler.RejectCase)
+
+		api.GET("/audits", handler.ListAudits)
+		api.GET("/audits/verify", handler.VerifyAuditChain)
+		api.GET("/audits/:id", handler.GetAudit)
+
+		api.GET("/reports/shadow", handler.ShadowReport)
+		api.POST("/reports/backtest", handler.Backtest)
 	}
 
 	r.GET("/docs/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	r.GET("/debug/vars", gin.WrapH(expvar.Handler()))
 
 	addr := fmt.Sprintf(":%s", cfg.Port)
 	logrus.Infof("starting server on %s", addr)
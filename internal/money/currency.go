@@ -0,0 +1,35 @@
+package money
+
+import "strings"
+
+// DefaultMinorUnits is the number of minor units of most currencies.
+const DefaultMinorUnits = 2
+
+// minorUnits lists the ISO 4217 currencies whose minor units differ from
+// DefaultMinorUnits.
+var minorUnits = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0,
+	"KRW": 0, "PYG": 0, "RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0,
+	"XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"CLF": 4, "UYW": 4,
+}
+
+// MinorUnits returns the number of decimal places used by an ISO 4217
+// currency code. Without a currency, any amount up to Scale is allowed.
+func MinorUnits(currency string) int {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency == "" {
+		return Scale
+	}
+	if n, ok := minorUnits[currency]; ok {
+		return n
+	}
+	return DefaultMinorUnits
+}
+
+// Fits reports whether a can be expressed in the minor units of currency,
+// so 10.5 fits USD but not JPY.
+func (a Amount) Fits(currency string) bool {
+	return a.Decimals() <= MinorUnits(currency)
+}
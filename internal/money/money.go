@@ -0,0 +1,246 @@
+// Package money provides a fixed-point decimal type for monetary amounts, so
+// thresholds compare and history sums add up exactly.
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// Scale is the number of decimal places an Amount keeps: the most minor units
+// any ISO 4217 currency has.
+const Scale = 4
+
+// unit is one whole currency unit in units of the last decimal place.
+const unit = 10000
+
+// maxExponent bounds the exponent accepted in a number such as 1e3.
+const maxExponent = 30
+
+var (
+	ErrSyntax    = errors.New("money: invalid amount")
+	ErrOverflow  = errors.New("money: amount out of range")
+	ErrPrecision = errors.New("money: amount has more than 4 decimal places")
+)
+
+// Amount is a decimal amount with Scale decimal places, held as an integer
+// count of its smallest unit. The zero value is 0.
+//
+// It reads from and writes to JSON as a plain number, and also accepts a
+// quoted decimal string. In the database it is a DECIMAL column.
+type Amount struct {
+	units int64
+}
+
+// Parse reads a decimal such as "1000.10", "-5" or "1.5e3". An amount with
+// non-zero digits beyond Scale decimal places fails with ErrPrecision rather
+// than being rounded, so "1.000049" is not read as 1.
+func Parse(s string) (Amount, error) {
+	return parse(s, false)
+}
+
+// parse reads s as Parse does. With round, digits beyond Scale decimal places
+// are rounded half away from zero instead of refused.
+func parse(s string, round bool) (Amount, error) {
+	in := s
+	neg := false
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		neg = s[0] == '-'
+		s = s[1:]
+	}
+	exp := 0
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		e, err := strconv.Atoi(s[i+1:])
+		if err != nil || e < -maxExponent || e > maxExponent {
+			return Amount{}, fmt.Errorf("%w: %q", ErrSyntax, in)
+		}
+		exp, s = e, s[:i]
+	}
+	whole, frac := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		whole, frac = s[:i], s[i+1:]
+	}
+	if (whole == "" && frac == "") || !digits(whole) || !digits(frac) {
+		return Amount{}, fmt.Errorf("%w: %q", ErrSyntax, in)
+	}
+
+	// The value is all digits times 10^shift units.
+	all := strings.TrimLeft(whole+frac, "0")
+	shift := exp - len(frac) + Scale
+	roundUp := false
+	if shift >= 0 {
+		if all != "" {
+			all += strings.Repeat("0", shift)
+		}
+	} else {
+		cut := len(all) + shift
+		if cut < 0 {
+			if !round && all != "" {
+				return Amount{}, fmt.Errorf("%w: %q", ErrPrecision, in)
+			}
+			all = ""
+		} else {
+			if !round && strings.Trim(all[cut:], "0") != "" {
+				return Amount{}, fmt.Errorf("%w: %q", ErrPrecision, in)
+			}
+			roundUp = cut < len(all) && all[cut] >= '5'
+			all = all[:cut]
+		}
+	}
+	if all == "" {
+		all = "0"
+	}
+	u, err := strconv.ParseInt(all, 10, 64)
+	if err != nil || (roundUp && u == math.MaxInt64) {
+		return Amount{}, fmt.Errorf("%w: %q", ErrOverflow, in)
+	}
+	if roundUp {
+		u++
+	}
+	if neg {
+		u = -u
+	}
+	return Amount{units: u}, nil
+}
+
+func digits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// MustParse is like Parse but panics on error. It is meant for constants.
+func MustParse(s string) Amount {
+	a, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+// FromFloat converts f to the nearest Amount, reading it the way it would be
+// printed, so 1000.1 becomes exactly 1000.1.
+func FromFloat(f float64) (Amount, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return Amount{}, fmt.Errorf("%w: %v", ErrSyntax, f)
+	}
+	return parse(strconv.FormatFloat(f, 'g', -1, 64), true)
+}
+
+// Float64 returns the nearest float64, for scoring and expressions.
+func (a Amount) Float64() float64 {
+	return float64(a.units) / unit
+}
+
+// String formats a without trailing zeros, such as "1000.1" or "-5".
+func (a Amount) String() string {
+	u := uint64(a.units)
+	if a.units < 0 {
+		u = -u
+	}
+	s := strconv.FormatUint(u/unit, 10)
+	if f := u % unit; f != 0 {
+		s += "." + strings.TrimRight(fmt.Sprintf("%0*d", Scale, f), "0")
+	}
+	if a.units < 0 {
+		s = "-" + s
+	}
+	return s
+}
+
+// Decimals returns how many decimal places a needs, from 0 to Scale.
+func (a Amount) Decimals() int {
+	s := a.String()
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		return len(s) - i - 1
+	}
+	return 0
+}
+
+// Cmp returns -1, 0 or +1 as a is less than, equal to or greater than b.
+func (a Amount) Cmp(b Amount) int {
+	switch {
+	case a.units < b.units:
+		return -1
+	case a.units > b.units:
+		return 1
+	}
+	return 0
+}
+
+// IsZero reports whether a is 0.
+func (a Amount) IsZero() bool { return a.units == 0 }
+
+// Sign returns -1, 0 or +1 depending on the sign of a.
+func (a Amount) Sign() int { return a.Cmp(Amount{}) }
+
+func (a Amount) Add(b Amount) Amount { return Amount{units: a.units + b.units} }
+
+func (a Amount) Sub(b Amount) Amount { return Amount{units: a.units - b.units} }
+
+// Mul returns a times f, rounded to Scale decimal places.
+func (a Amount) Mul(f float64) Amount {
+	return Amount{units: int64(math.Round(float64(a.units) * f))}
+}
+
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalJSON accepts a JSON number or a string holding a decimal. null
+// leaves a unchanged.
+func (a *Amount) UnmarshalJSON(b []byte) error {
+	s := string(b)
+	if s == "null" {
+		return nil
+	}
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		s = strings.TrimSpace(s[1 : len(s)-1])
+	}
+	v, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
+
+// Value stores a as a decimal string.
+func (a Amount) Value() (driver.Value, error) {
+	return a.String(), nil
+}
+
+// Scan reads a DECIMAL, or the float or integer a column held before.
+func (a *Amount) Scan(src any) error {
+	var err error
+	switch v := src.(type) {
+	case nil:
+		*a = Amount{}
+	case []byte:
+		*a, err = Parse(string(v))
+	case string:
+		*a, err = Parse(v)
+	case float64:
+		*a, err = FromFloat(v)
+	case int64:
+		*a, err = Parse(strconv.FormatInt(v, 10))
+	default:
+		err = fmt.Errorf("money: cannot scan %T", src)
+	}
+	return err
+}
+
+// GormDBDataType makes Amount columns DECIMAL with Scale decimal places.
+func (Amount) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	return fmt.Sprintf("DECIMAL(19,%d)", Scale)
+}
@@ -0,0 +1,173 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParseRefusesExtraDecimals(t *testing.T) {
+	for _, in := range []string{"1.000049", "1.00005", "0.00001", "1e-5", "-2.12345"} {
+		if _, err := Parse(in); !errors.Is(err, ErrPrecision) {
+			t.Errorf("Parse(%q): err = %v, want ErrPrecision", in, err)
+		}
+	}
+	var tx struct{ Amount Amount }
+	if err := json.Unmarshal([]byte(`{"Amount": 1.000049}`), &tx); !errors.Is(err, ErrPrecision) {
+		t.Errorf("unmarshal 1.000049: err = %v, want ErrPrecision", err)
+	}
+	// trailing zeros past Scale change nothing
+	if a, err := Parse("1.50000000"); err != nil || a.String() != "1.5" {
+		t.Errorf("Parse(1.50000000) = %v, %v, want 1.5", a, err)
+	}
+	// floats are rounded to the nearest Amount
+	if a, err := FromFloat(0.1 + 0.2); err != nil || a.String() != "0.3" {
+		t.Errorf("FromFloat(0.1+0.2) = %v, %v, want 0.3", a, err)
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want string
+		err  error
+	}{
+		{"1000.10", "1000.1", nil},
+		{"-5", "-5", nil},
+		{"+5.25", "5.25", nil},
+		{"1.5e3", "1500", nil},
+		{"15E-1", "1.5", nil},
+		{".5", "0.5", nil},
+		{"5.", "5", nil},
+		{"-0.0001", "-0.0001", nil},
+		{"-0", "0", nil},
+		{"922337203685477.5807", "922337203685477.5807", nil},
+		{"-922337203685477.5807", "-922337203685477.5807", nil},
+		{"922337203685477.5808", "", ErrOverflow},
+		{"1e20", "", ErrOverflow},
+		{"", "", ErrSyntax},
+		{".", "", ErrSyntax},
+		{"1,5", "", ErrSyntax},
+		{"--1", "", ErrSyntax},
+		{"1e", "", ErrSyntax},
+		{"1e31", "", ErrSyntax},
+		{"NaN", "", ErrSyntax},
+	} {
+		a, err := Parse(tc.in)
+		if tc.err != nil {
+			if !errors.Is(err, tc.err) {
+				t.Errorf("Parse(%q): err = %v, want %v", tc.in, err, tc.err)
+			}
+			continue
+		}
+		if err != nil || a.String() != tc.want {
+			t.Errorf("Parse(%q) = %v, %v, want %s", tc.in, a, err, tc.want)
+		}
+	}
+}
+
+func TestFromFloatRounds(t *testing.T) {
+	for _, tc := range []struct {
+		in   float64
+		want string
+	}{
+		{1000.1, "1000.1"},
+		{0.00005, "0.0001"},
+		{0.00004, "0"},
+		{-0.00005, "-0.0001"},
+		{2.34565, "2.3457"},
+	} {
+		if a, err := FromFloat(tc.in); err != nil || a.String() != tc.want {
+			t.Errorf("FromFloat(%v) = %v, %v, want %s", tc.in, a, err, tc.want)
+		}
+	}
+	if _, err := FromFloat(1e20); !errors.Is(err, ErrOverflow) {
+		t.Errorf("FromFloat(1e20): err = %v, want ErrOverflow", err)
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	a, b := MustParse("0.1"), MustParse("0.2")
+	if got := a.Add(b); got.Cmp(MustParse("0.3")) != 0 {
+		t.Errorf("0.1 + 0.2 = %v", got)
+	}
+	if got := a.Sub(b); got.String() != "-0.1" || got.Sign() != -1 {
+		t.Errorf("0.1 - 0.2 = %v", got)
+	}
+	if got := MustParse("10.01").Mul(1.5); got.String() != "15.015" {
+		t.Errorf("10.01 * 1.5 = %v", got)
+	}
+	if MustParse("1000.10").Cmp(MustParse("1000.1")) != 0 || !MustParse("0.000").IsZero() {
+		t.Error("equal amounts compare unequal")
+	}
+}
+
+func TestFits(t *testing.T) {
+	for _, tc := range []struct {
+		amount, currency string
+		want             bool
+	}{
+		{"10.5", "USD", true},
+		{"10.55", "usd", true},
+		{"10.555", "USD", false},
+		{"10", "JPY", true},
+		{"10.5", "JPY", false},
+		{"-10.5", "KRW", false},
+		{"1.234", "KWD", true},
+		{"1.2345", "KWD", false},
+		{"1.2345", "CLF", true},
+		{"1.25", "XYZ", true},
+		{"1.255", "XYZ", false},
+		{"1.2345", "", true},
+	} {
+		if got := MustParse(tc.amount).Fits(tc.currency); got != tc.want {
+			t.Errorf("%s fits %q = %v, want %v", tc.amount, tc.currency, got, tc.want)
+		}
+	}
+}
+
+func TestMinorUnits(t *testing.T) {
+	for currency, want := range map[string]int{
+		"USD": 2, "EUR": 2, "JPY": 0, " jpy ": 0, "BHD": 3, "CLF": 4, "": Scale,
+	} {
+		if got := MinorUnits(currency); got != want {
+			t.Errorf("MinorUnits(%q) = %d, want %d", currency, got, want)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	var v struct{ A, B, C Amount }
+	v.C = MustParse("7")
+	if err := json.Unmarshal([]byte(`{"A": 1000.10, "B": " -2.5 ", "C": null}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"A":1000.1,"B":-2.5,"C":7}` {
+		t.Errorf("round trip = %s", out)
+	}
+	if err := json.Unmarshal([]byte(`{"A": "ten"}`), &v); !errors.Is(err, ErrSyntax) {
+		t.Errorf("unmarshal ten: err = %v, want ErrSyntax", err)
+	}
+}
+
+func TestScan(t *testing.T) {
+	for _, tc := range []struct {
+		src  any
+		want string
+	}{
+		{[]byte("12.5000"), "12.5"},
+		{"12.5", "12.5"},
+		{12.5, "12.5"},
+		{int64(12), "12"},
+		{nil, "0"},
+	} {
+		a := MustParse("1")
+		if err := a.Scan(tc.src); err != nil || a.String() != tc.want {
+			t.Errorf("Scan(%#v) = %v, %v, want %s", tc.src, a, err, tc.want)
+		}
+	}
+}
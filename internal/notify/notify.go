@@ -0,0 +1,100 @@
+// Package notify delivers JSON callbacks to caller-supplied URLs.
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	attempts     = 3
+	firstBackoff = time.Second
+)
+
+// client does not follow redirects, which could lead a callback to a host
+// that is not allowed.
+var client = &http.Client{
+	Timeout: 10 * time.Second,
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// Allowlist names the hosts callbacks may be sent to. An entry matches that
+// host exactly; an entry starting with a dot, such as ".example.com", matches
+// any of its subdomains. An empty list allows no callbacks.
+type Allowlist []string
+
+// ParseAllowlist reads a comma separated list of hosts.
+func ParseAllowlist(s string) Allowlist {
+	var out Allowlist
+	for _, h := range strings.Split(s, ",") {
+		if h = strings.ToLower(strings.TrimSpace(h)); h != "" {
+			out = append(out, h)
+		}
+	}
+	return out
+}
+
+// Allows reports whether raw is an absolute http(s) URL whose host is on the
+// list.
+func (a Allowlist) Allows(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	if host == "" {
+		return false
+	}
+	for _, h := range a {
+		if host == h || (strings.HasPrefix(h, ".") && strings.HasSuffix(host, h)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Post sends payload as JSON to url in the background, retrying with backoff
+// on network errors and non-2xx responses. Failures are logged, not returned.
+func Post(url string, payload any) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		logrus.WithError(err).WithField("url", url).Warn("callback payload could not be encoded")
+		return
+	}
+	go func() {
+		backoff := firstBackoff
+		for i := 1; ; i++ {
+			err := send(url, body)
+			if err == nil {
+				return
+			}
+			if i == attempts {
+				logrus.WithError(err).WithField("url", url).Warn("callback delivery failed")
+				return
+			}
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}()
+}
+
+func send(url string, body []byte) error {
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("callback returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package notify
+
+import "testing"
+
+func TestAllowlistAllows(t *testing.T) {
+	a := ParseAllowlist(" hooks.example.com, .partner.io ,")
+	for _, tc := range []struct {
+		url  string
+		want bool
+	}{
+		{"https://hooks.example.com/done", true},
+		{"http://HOOKS.example.com:8443/done", true},
+		{"https://api.partner.io/cb", true},
+		{"https://a.b.partner.io/cb", true},
+		{"https://partner.io/cb", false},
+		{"https://evilpartner.io/cb", false},
+		{"https://example.com/done", false},
+		{"https://hooks.example.com@127.0.0.1/done", false},
+		{"http://127.0.0.1/admin", false},
+		{"http://169.254.169.254/latest/meta-data", false},
+		{"ftp://hooks.example.com/done", false},
+		{"/relative", false},
+		{"", false},
+	} {
+		if got := a.Allows(tc.url); got != tc.want {
+			t.Errorf("Allows(%q) = %v, want %v", tc.url, got, tc.want)
+		}
+	}
+	if ParseAllowlist("").Allows("https://hooks.example.com/done") {
+		t.Error("an empty allowlist allowed a callback")
+	}
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/warleon/ms4-compliance-service/internal/dto"
+	"github.com/warleon/ms4-compliance-service/internal/repository/rules"
+)
+
+// AuditChainHead holds the hash of the newest audit entry. Its single row is
+// locked while appending so entries are chained one at a time.
+type AuditChainHead struct {
+	ID      uint   `gorm:"primaryKey"`
+	AuditID uint   `json:"auditId"`
+	Hash    string `gorm:"size:64" json:"hash"`
+}
+
+// auditChainHeadID is the primary key of the only AuditChainHead row.
+const auditChainHeadID = 1
+
+// hashedAudit is the canonical content covered by an audit entry's hash.
+type hashedAudit struct {
+	PrevHash      string               `json:"prevHash"`
+	CreatedAt     int64                `json:"createdAt"`
+	TransactionID string               `json:"transactionId"`
+	CustomerID    string               `json:"customerId"`
+	Approved      bool                 `json:"approved"`
+	Status        rules.DecisionStatus `json:"status"`
+	Reason        string               `json:"reason"`
+	RiskScore     float64              `json:"riskScore,omitempty"`
+	Matches       []rules.NameMatch    `json:"matches,omitempty"`
+	Transaction   dto.Transaction      `json:"transaction"`
+	Trace         []rules.RuleResult   `json:"trace"`
+}
+
+// ComputeHash returns the SHA-256 over the entry's content and PrevHash, hex encoded.
+// CreatedAt is taken at millisecond precision, which is what the database keeps.
+// Transaction and Trace are hashed as they read back from their JSON columns,
+// where metadata and trace inputs hold plain JSON values.
+func (a *AuditLog) ComputeHash() (string, error) {
+	tx, err := asStored(a.Transaction)
+	if err != nil {
+		return "", err
+	}
+	trace, err := asStored(a.Trace)
+	if err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(hashedAudit{
+		PrevHash:      a.PrevHash,
+		CreatedAt:     a.CreatedAt.UnixMilli(),
+		TransactionID: a.TransactionID,
+		CustomerID:    a.CustomerID,
+		Approved:      a.Decision.Approved,
+		Status:        a.Decision.Status,
+		Reason:        a.Decision.Reason,
+		RiskScore:     a.Decision.RiskScore,
+		Matches:       a.Decision.Matches,
+		Transaction:   tx,
+		Trace:         trace,
+	})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// asStored returns v as the JSON serializer reads it back from the database,
+// so a value hashes the same before it is written and after it is loaded.
+func asStored[T any](v T) (T, error) {
+	var out T
+	b, err := json.Marshal(v)
+	if err != nil {
+		return out, err
+	}
+	err = json.Unmarshal(b, &out)
+	return out, err
+}
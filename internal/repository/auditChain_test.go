@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/warleon/ms4-compliance-service/internal/dto"
+	"github.com/warleon/ms4-compliance-service/internal/money"
+	"github.com/warleon/ms4-compliance-service/internal/repository/rules"
+)
+
+// reload returns a as it reads back from the database, with the JSON columns
+// decoded by the serializer.
+func reload(t *testing.T, a AuditLog) AuditLog {
+	t.Helper()
+	for _, v := range []any{&a.Transaction, &a.Trace} {
+		b, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(b, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return a
+}
+
+func TestComputeHashSurvivesReload(t *testing.T) {
+	big := money.MustParse("123456789012345.6789")
+	a := AuditLog{
+		TransactionID: "tx-1",
+		PrevHash:      "abc",
+		Decision:      rules.NewDecision(rules.StatusManualReview, "Party name resembles sanctioned party X"),
+		Transaction: dto.Transaction{
+			ID: "tx-1", Amount: big, Currency: "USD",
+			Metadata: map[string]any{"partyName": "Jon Doe", "count": 3, "nested": map[string]any{"a": []int{1}}},
+		},
+		Trace: []rules.RuleResult{{
+			RuleID: 1, Name: "names", Type: rules.RuleTypeNameScreening, Status: rules.StatusManualReview,
+			Inputs: map[string]any{
+				"matches": []rules.NameMatch{{SanctionID: 7, Name: "John Doe", MatchedName: "John Doe", Score: 0.93}},
+				"amount":  big,
+			},
+		}},
+	}
+	a.CreatedAt = time.Now().Truncate(time.Millisecond)
+
+	before, err := a.ComputeHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded := reload(t, a)
+	after, err := loaded.ComputeHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before != after {
+		t.Fatalf("hash changed on reload: %s, then %s", before, after)
+	}
+
+	a.Decision.Reason = "edited"
+	if edited, _ := a.ComputeHash(); edited == before {
+		t.Fatal("hash did not change with the content")
+	}
+}
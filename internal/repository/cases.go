@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CaseStatus is the state of a manual review case.
+type CaseStatus string
+
+const (
+	CaseOpen     CaseStatus = "open"
+	CaseAssigned CaseStatus = "assigned"
+	CaseApproved CaseStatus = "approved"
+	CaseRejected CaseStatus = "rejected"
+)
+
+// Closed reports whether the case has reached a final outcome.
+func (s CaseStatus) Closed() bool {
+	return s == CaseApproved || s == CaseRejected
+}
+
+// Case is a transaction held for manual review. It is opened from the audit
+// entry of a manual_review decision and closed when an analyst approves or
+// rejects it.
+type Case struct {
+	gorm.Model    `swaggerignore:"true"`
+	AuditID       uint         `gorm:"uniqueIndex" json:"auditId"`
+	TransactionID string       `gorm:"index" json:"transactionId"`
+	CustomerID    string       `gorm:"index" json:"customerId"`
+	Status        CaseStatus   `gorm:"size:16;index" json:"status"`
+	Reason        string       `gorm:"type:text" json:"reason"`
+	Assignee      string       `gorm:"size:100;index" json:"assignee,omitempty"`
+	ResolvedBy    string       `gorm:"size:100" json:"resolvedBy,omitempty"`
+	ResolvedAt    *time.Time   `json:"resolvedAt,omitempty"`
+	CallbackURL   string       `gorm:"size:2048" json:"callbackUrl,omitempty"`
+	Events        []AuditEvent `gorm:"foreignKey:CaseID" json:"events,omitempty"`
+}
+
+// Case event actions.
+const (
+	EventOpened    = "opened"
+	EventAssigned  = "assigned"
+	EventCommented = "commented"
+	EventApproved  = "approved"
+	EventRejected  = "rejected"
+)
+
+// AuditEvent records something that happened to an audit entry after the
+// decision was made, such as its review case being assigned or resolved.
+type AuditEvent struct {
+	gorm.Model `swaggerignore:"true"`
+	AuditID    uint       `gorm:"index" json:"auditId"`
+	CaseID     uint       `gorm:"index" json:"caseId"`
+	Action     string     `gorm:"size:32" json:"action"`
+	Actor      string     `gorm:"size:100" json:"actor,omitempty"`
+	Comment    string     `gorm:"type:text" json:"comment,omitempty"`
+	Status     CaseStatus `gorm:"size:16" json:"status"` // case status after the event
+}
+
+// CaseFilter narrows a case listing. Zero values are ignored.
+type CaseFilter struct {
+	AuditID       uint
+	Status        CaseStatus
+	Assignee      string
+	TransactionID string
+	Size          int
+	Offset        int
+}
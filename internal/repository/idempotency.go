@@ -0,0 +1,14 @@
+package repository
+
+import "time"
+
+// IdempotencyKey remembers the validation of a transaction ID so a retried
+// request gets the same decision. PayloadHash tells a retry from a different
+// transaction reusing the ID.
+type IdempotencyKey struct {
+	ID            uint      `gorm:"primaryKey"`
+	TransactionID string    `gorm:"size:255;uniqueIndex"`
+	PayloadHash   string    `gorm:"size:64"`
+	AuditID       uint      // audit entry holding the decision
+	CreatedAt     time.Time `gorm:"index"`
+}
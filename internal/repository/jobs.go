@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// JobKind is what a validation job screens.
+type JobKind string
+
+const (
+	JobTransactions JobKind = "transactions"
+	JobAccounts     JobKind = "accounts"
+)
+
+// Valid reports whether k is a known job kind.
+func (k JobKind) Valid() bool {
+	return k == JobTransactions || k == JobAccounts
+}
+
+// JobStatus is the state of a validation job.
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobCompleted JobStatus = "completed"
+)
+
+// ErrJobLeaseLost is returned when a process no longer holds a job's lease,
+// because it expired and another process claimed the job.
+var ErrJobLeaseLost = errors.New("job lease lost")
+
+// ErrJobItemDone is returned when a job item already has a result, stored by
+// a process that held the job before.
+var ErrJobItemDone = errors.New("job item already screened")
+
+// Job is an uploaded file of transactions or accounts screened in the
+// background. Its items are stored with it, so a job interrupted by a restart
+// resumes from the first item without a result. Owner is the process working
+// on the job, which holds it until LeaseUntil; a job whose lease expired can
+// be claimed by another process.
+type Job struct {
+	gorm.Model  `swaggerignore:"true"`
+	Kind        JobKind    `gorm:"size:16" json:"kind"`
+	Status      JobStatus  `gorm:"size:16;index" json:"status"`
+	Mode        string     `gorm:"size:16" json:"mode,omitempty"` // evaluation mode of a transactions job
+	CallbackURL string     `gorm:"size:2048" json:"callbackUrl,omitempty"`
+	Total       int        `json:"total"`
+	Processed   int        `json:"processed"`
+	Failed      int        `json:"failed"` // items that could not be screened
+	StartedAt   *time.Time `json:"startedAt,omitempty"`
+	FinishedAt  *time.Time `json:"finishedAt,omitempty"`
+	Owner       string     `gorm:"size:128" json:"-"`
+	LeaseUntil  *time.Time `gorm:"index" json:"-"`
+}
+
+// JobItem is one transaction or account of a job and, once Done, its result.
+// Failed marks a result that holds an error instead of a screening outcome.
+type JobItem struct {
+	ID     uint            `gorm:"primaryKey"`
+	JobID  uint            `gorm:"uniqueIndex:idx_job_item_seq"`
+	Seq    int             `gorm:"uniqueIndex:idx_job_item_seq"` // position in the uploaded file, from 1
+	Input  json.RawMessage `gorm:"type:json"`
+	Result json.RawMessage `gorm:"type:json"`
+	Done   bool
+	Failed bool
+}
@@ -1,14 +1,26 @@
 package repository
 
 import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/warleon/ms4-compliance-service/internal/money"
 	"github.com/warleon/ms4-compliance-service/internal/repository/rules"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type mysqlRepo struct {
 	db *gorm.DB
 }
 
+func (r *mysqlRepo) WithTx(fn func(repo Repository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&mysqlRepo{db: tx})
+	})
+}
+
 func (r *mysqlRepo) CreateRule(rule *rules.Rule) error {
 	return r.db.Create(rule).Error
 }
@@ -22,16 +34,56 @@ func (r *mysqlRepo) ReadRule(id uint) (*rules.Rule, error) {
 	return &rule, nil
 }
 
-func (r *mysqlRepo) ReadRules(size int, offset int) ([]rules.Rule, error) {
+func (r *mysqlRepo) ReadRules(f RuleFilter) ([]rules.Rule, error) {
+	q := r.db
+	// the periods of rules.RuleBase.Period
+	switch f.Period {
+	case rules.PeriodUpcoming:
+		q = q.Where("effective_from > ?", f.At)
+	case rules.PeriodActive:
+		q = q.Where("(effective_from IS NULL OR effective_from <= ?) AND (effective_to IS NULL OR effective_to > ?)", f.At, f.At)
+	case rules.PeriodExpired:
+		q = q.Where("effective_to <= ?", f.At)
+	}
 	var out []rules.Rule
-	if err := r.db.Offset(offset).Limit(size).Find(&out).Error; err != nil {
+	if err := q.Offset(f.Offset).Limit(f.Size).Find(&out).Error; err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func (r *mysqlRepo) UpdateRule(rule *rules.Rule) error {
-	return r.db.Updates(rule).Error
+func (r *mysqlRepo) SaveRule(rule *rules.Rule) error {
+	return r.db.Save(rule).Error
+}
+
+func (r *mysqlRepo) LockRule(id uint) (*rules.Rule, error) {
+	var rule rules.Rule
+	err := r.db.Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate}).First(&rule, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (r *mysqlRepo) CreateRuleVersion(v *RuleVersion) error {
+	return r.db.Create(v).Error
+}
+
+func (r *mysqlRepo) ReadRuleVersions(ruleID uint) ([]RuleVersion, error) {
+	var out []RuleVersion
+	if err := r.db.Where("rule_id = ?", ruleID).Order("version DESC").Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *mysqlRepo) ReadRuleVersion(ruleID, version uint) (*RuleVersion, error) {
+	var v RuleVersion
+	err := r.db.Where("rule_id = ? AND version = ?", ruleID, version).First(&v).Error
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
 }
 
 func (r *mysqlRepo) DeleteRule(id uint) error {
@@ -39,7 +91,32 @@ func (r *mysqlRepo) DeleteRule(id uint) error {
 }
 
 func (r *mysqlRepo) CreateAudit(audit *AuditLog) error {
-	return r.db.Create(audit).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// lock the chain head so concurrent writers append one after another
+		err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&AuditChainHead{ID: auditChainHeadID}).Error
+		if err != nil {
+			return err
+		}
+		var head AuditChainHead
+		err = tx.Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate}).
+			First(&head, auditChainHeadID).Error
+		if err != nil {
+			return err
+		}
+
+		audit.PrevHash = head.Hash
+		audit.CreatedAt = time.Now().Truncate(time.Millisecond)
+		if audit.Hash, err = audit.ComputeHash(); err != nil {
+			return err
+		}
+		if err := tx.Create(audit).Error; err != nil {
+			return err
+		}
+		head.AuditID = audit.ID
+		head.Hash = audit.Hash
+		return tx.Save(&head).Error
+	})
 }
 
 func (r *mysqlRepo) ReadAuidits(size int, offset int) ([]AuditLog, error) {
@@ -50,6 +127,84 @@ func (r *mysqlRepo) ReadAuidits(size int, offset int) ([]AuditLog, error) {
 	return out, nil
 }
 
+func (r *mysqlRepo) ReadAudit(id uint) (*AuditLog, error) {
+	var audit AuditLog
+	err := r.db.Joins("Decision").First(&audit, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &audit, nil
+}
+
+// traceRuleIDPaths lists the JSON paths of the rule IDs in a trace: those of
+// the entries and, level by level, of the children of composite rules, down
+// to the deepest nesting allowed.
+var traceRuleIDPaths = func() string {
+	paths := make([]string, rules.MaxCompositeDepth+1)
+	path := "$[*]"
+	for i := range paths {
+		paths[i] = "'" + path + ".ruleId'"
+		path += ".children[*]"
+	}
+	return strings.Join(paths, ", ")
+}()
+
+func (r *mysqlRepo) FindAudits(f AuditFilter) ([]AuditLog, error) {
+	q := r.db.Joins("Decision")
+	if f.CustomerID != "" {
+		q = q.Where("audit_logs.customer_id = ?", f.CustomerID)
+	}
+	if f.TransactionID != "" {
+		q = q.Where("audit_logs.transaction_id = ?", f.TransactionID)
+	}
+	if f.Status != "" {
+		q = q.Where("Decision.status = ?", f.Status)
+	}
+	if f.RuleID != 0 {
+		q = q.Where("JSON_CONTAINS(JSON_EXTRACT(audit_logs.trace, "+traceRuleIDPaths+"), CAST(? AS JSON))", f.RuleID)
+	}
+	if f.Shadow {
+		q = q.Where("JSON_CONTAINS(audit_logs.trace, JSON_OBJECT('shadow', true))")
+	}
+	if f.From != nil {
+		q = q.Where("audit_logs.created_at >= ?", *f.From)
+	}
+	if f.To != nil {
+		q = q.Where("audit_logs.created_at < ?", *f.To)
+	}
+	if f.Cursor != 0 {
+		q = q.Where("audit_logs.id < ?", f.Cursor)
+	}
+	var out []AuditLog
+	if err := q.Order("audit_logs.id DESC").Limit(f.Size).Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *mysqlRepo) ScanAudits(afterID uint, size int) ([]AuditLog, error) {
+	var out []AuditLog
+	err := r.db.Unscoped().Joins("Decision").
+		Where("audit_logs.id > ?", afterID).
+		Order("audit_logs.id").Limit(size).Find(&out).Error
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *mysqlRepo) ReadAuditChainHead() (*AuditChainHead, error) {
+	var head AuditChainHead
+	err := r.db.First(&head, auditChainHeadID).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return &AuditChainHead{ID: auditChainHeadID}, nil
+		}
+		return nil, err
+	}
+	return &head, nil
+}
+
 func (r *mysqlRepo) FindRulesByType(ruleType string) ([]rules.Rule, error) {
 	var out []rules.Rule
 	if err := r.db.Where("type = ?", ruleType).Find(&out).Error; err != nil {
@@ -58,8 +213,20 @@ func (r *mysqlRepo) FindRulesByType(ruleType string) ([]rules.Rule, error) {
 	return out, nil
 }
 
+func (r *mysqlRepo) FindRules() ([]rules.Rule, error) {
+	var out []rules.Rule
+	if err := r.db.Order("id").Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (r *mysqlRepo) IsAccountSanctioned(accID string) (bool, error) {
 	var s rules.Sanction
+	if accID == "" {
+		// an empty struct condition would match any row
+		return false, nil
+	}
 	err := r.db.Where(&rules.Sanction{AccID: accID}).First(&s).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
@@ -70,6 +237,300 @@ func (r *mysqlRepo) IsAccountSanctioned(accID string) (bool, error) {
 	return true, nil
 }
 
+func (r *mysqlRepo) ListSanctionedAccounts() ([]string, error) {
+	var out []string
+	err := r.db.Model(&rules.Sanction{}).
+		Where("acc_id <> ''").
+		Distinct().Pluck("acc_id", &out).Error
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *mysqlRepo) FindSanctionedParties() ([]rules.Sanction, error) {
+	var out []rules.Sanction
+	if err := r.db.Where("name <> ''").Find(&out).Error; err != nil {
+		return nil, err
+	}
+	rules.NormalizeNames(out)
+	return out, nil
+}
+
+func (r *mysqlRepo) CreateSanction(s *rules.Sanction) error {
+	return r.db.Create(s).Error
+}
+
+func (r *mysqlRepo) ReadSanction(id uint) (*rules.Sanction, error) {
+	var s rules.Sanction
+	err := r.db.First(&s, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *mysqlRepo) ReadSanctions(size int, offset int) ([]rules.Sanction, error) {
+	var out []rules.Sanction
+	if err := r.db.Offset(offset).Limit(size).Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *mysqlRepo) DeleteSanction(id uint) error {
+	return r.db.Delete(&rules.Sanction{}, id).Error
+}
+
+func (r *mysqlRepo) FindSanctionedAccounts(accIDs []string) ([]string, error) {
+	var out []string
+	if len(accIDs) == 0 {
+		return out, nil
+	}
+	err := r.db.Model(&rules.Sanction{}).
+		Where("acc_id IN ?", accIDs).
+		Distinct().Pluck("acc_id", &out).Error
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *mysqlRepo) CreateSanctions(s []rules.Sanction) error {
+	if len(s) == 0 {
+		return nil
+	}
+	return r.db.CreateInBatches(s, 500).Error
+}
+
+func (r *mysqlRepo) DeleteSanctionsBySource(source rules.SanctionSource) (int64, error) {
+	res := r.db.Where("source = ?", source).Delete(&rules.Sanction{})
+	return res.RowsAffected, res.Error
+}
+
+func (r *mysqlRepo) CreateCase(c *Case) error {
+	return r.db.Create(c).Error
+}
+
+func (r *mysqlRepo) ReadCase(id uint) (*Case, error) {
+	var c Case
+	err := r.db.Preload("Events", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).First(&c, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *mysqlRepo) LockCase(id uint) (*Case, error) {
+	var c Case
+	err := r.db.Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate}).First(&c, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *mysqlRepo) FindCases(f CaseFilter) ([]Case, error) {
+	q := r.db.Model(&Case{})
+	if f.AuditID != 0 {
+		q = q.Where("audit_id = ?", f.AuditID)
+	}
+	if f.Status != "" {
+		q = q.Where("status = ?", f.Status)
+	}
+	if f.Assignee != "" {
+		q = q.Where("assignee = ?", f.Assignee)
+	}
+	if f.TransactionID != "" {
+		q = q.Where("transaction_id = ?", f.TransactionID)
+	}
+	var out []Case
+	if err := q.Order("id").Offset(f.Offset).Limit(f.Size).Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *mysqlRepo) UpdateCase(c *Case) error {
+	return r.db.Omit("Events").Save(c).Error
+}
+
+func (r *mysqlRepo) CreateAuditEvent(e *AuditEvent) error {
+	return r.db.Create(e).Error
+}
+
+func (r *mysqlRepo) CreateTransaction(t *TransactionRecord) error {
+	return r.db.Create(t).Error
+}
+
+func (r *mysqlRepo) TransactionStats(f TransactionFilter) (int64, money.Amount, error) {
+	if !f.Key.Valid() {
+		return 0, money.Amount{}, fmt.Errorf("invalid velocity key %q", f.Key)
+	}
+	var out struct {
+		Count int64
+		Total money.Amount
+	}
+	q := r.db.Model(&TransactionRecord{}).
+		Select("COUNT(*) AS count, COALESCE(SUM(amount), 0) AS total").
+		Where(string(f.Key)+" = ?", f.Value).
+		Where("created_at >= ?", f.Since)
+	if f.Currency != "" {
+		q = q.Where("currency = ?", f.Currency)
+	}
+	if !f.Until.IsZero() {
+		q = q.Where("created_at < ?", f.Until)
+	}
+	// Amounts are bound as decimal strings; cast them so MySQL compares
+	// decimals rather than doubles.
+	if f.MinAmount != nil {
+		q = q.Where("amount >= CAST(? AS DECIMAL(19,4))", *f.MinAmount)
+	}
+	if f.MaxAmount != nil {
+		q = q.Where("amount < CAST(? AS DECIMAL(19,4))", *f.MaxAmount)
+	}
+	err := q.Scan(&out).Error
+	if err != nil {
+		return 0, money.Amount{}, err
+	}
+	return out.Count, out.Total, nil
+}
+
 func NewMySQLRepository(db *gorm.DB) Repository {
 	return &mysqlRepo{db: db}
 }
+
+func (r *mysqlRepo) ClaimIdempotencyKey(k *IdempotencyKey) (*IdempotencyKey, error) {
+	res := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(k)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 1 {
+		return nil, nil
+	}
+	var existing IdempotencyKey
+	err := r.db.Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate}).
+		Where("transaction_id = ?", k.TransactionID).First(&existing).Error
+	if err != nil {
+		return nil, err
+	}
+	return &existing, nil
+}
+
+func (r *mysqlRepo) SaveIdempotencyKey(k *IdempotencyKey) error {
+	return r.db.Save(k).Error
+}
+
+func (r *mysqlRepo) DeleteIdempotencyKeys(before time.Time) (int64, error) {
+	res := r.db.Where("created_at < ?", before).Delete(&IdempotencyKey{})
+	return res.RowsAffected, res.Error
+}
+
+func (r *mysqlRepo) CreateJob(j *Job) error {
+	return r.db.Create(j).Error
+}
+
+func (r *mysqlRepo) CreateJobItems(items []JobItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+	return r.db.CreateInBatches(items, 500).Error
+}
+
+func (r *mysqlRepo) ReadJob(id uint) (*Job, error) {
+	var j Job
+	if err := r.db.First(&j, id).Error; err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+func (r *mysqlRepo) UpdateJob(j *Job) error {
+	return r.db.Save(j).Error
+}
+
+func (r *mysqlRepo) ClaimJob(owner string, lease time.Duration) (*Job, error) {
+	var j Job
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		// skip jobs another process is claiming right now
+		err := tx.Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate, Options: clause.LockingOptionsSkipLocked}).
+			Where("status IN ?", []JobStatus{JobQueued, JobRunning}).
+			Where("owner = ? OR lease_until IS NULL OR lease_until < ?", owner, now).
+			Order("id").First(&j).Error
+		if err != nil {
+			return err
+		}
+		until := now.Add(lease)
+		j.Owner, j.LeaseUntil = owner, &until
+		return tx.Model(&j).Updates(map[string]any{"owner": owner, "lease_until": until}).Error
+	})
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+func (r *mysqlRepo) HoldJob(j *Job, owner string, lease time.Duration) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var cur Job
+		err := tx.Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate}).
+			Select("id", "owner").First(&cur, j.ID).Error
+		if err != nil {
+			return err
+		}
+		if cur.Owner != owner {
+			return ErrJobLeaseLost
+		}
+		until := time.Now().Add(lease)
+		if err := tx.Model(&cur).UpdateColumn("lease_until", until).Error; err != nil {
+			return err
+		}
+		j.Owner, j.LeaseUntil = owner, &until
+		return nil
+	})
+}
+
+func (r *mysqlRepo) PendingJobItems(jobID uint, size int) ([]JobItem, error) {
+	var out []JobItem
+	err := r.db.Where("job_id = ? AND done = ?", jobID, false).Order("seq").Limit(size).Find(&out).Error
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *mysqlRepo) FinishJobItem(it *JobItem) error {
+	res := r.db.Model(&JobItem{}).Where("id = ? AND done = ?", it.ID, false).
+		Updates(map[string]any{"result": it.Result, "failed": it.Failed, "done": true})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrJobItemDone
+	}
+	it.Done = true
+	return nil
+}
+
+func (r *mysqlRepo) JobProgress(jobID uint) (int, int, error) {
+	var p struct{ Processed, Failed int }
+	err := r.db.Model(&JobItem{}).Where("job_id = ? AND done = ?", jobID, true).
+		Select("COUNT(*) AS processed, COALESCE(SUM(failed), 0) AS failed").Scan(&p).Error
+	return p.Processed, p.Failed, err
+}
+
+func (r *mysqlRepo) ReadJobResults(jobID uint, afterSeq, size int) ([]JobItem, error) {
+	var out []JobItem
+	err := r.db.Where("job_id = ? AND done = ? AND seq > ?", jobID, true, afterSeq).
+		Order("seq").Limit(size).Find(&out).Error
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
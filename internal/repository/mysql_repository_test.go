@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/warleon/ms4-compliance-service/internal/repository/rules"
+)
+
+// extract follows a JSON path made of [*] and .key steps, as JSON_EXTRACT
+// does, and returns every value it reaches.
+func extract(v any, path string) []any {
+	out := []any{v}
+	for _, step := range strings.Split(strings.ReplaceAll(path, "[*]", ".[*]"), ".")[1:] {
+		var next []any
+		for _, v := range out {
+			if step == "[*]" {
+				arr, _ := v.([]any)
+				next = append(next, arr...)
+			} else if e, ok := v.(map[string]any)[step]; ok {
+				next = append(next, e)
+			}
+		}
+		out = next
+	}
+	return out
+}
+
+func TestTraceRuleIDPaths(t *testing.T) {
+	// rule 1 is a composite nesting rules 2, 3, ... down to the deepest level
+	leaf := rules.RuleResult{RuleID: rules.MaxCompositeDepth + 1}
+	for id := uint(rules.MaxCompositeDepth); id >= 1; id-- {
+		leaf = rules.RuleResult{RuleID: id, Children: []rules.RuleResult{leaf}}
+	}
+	trace := []rules.RuleResult{{RuleID: 100}, leaf}
+	b, err := json.Marshal(trace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc any
+	if err := json.Unmarshal(b, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []uint
+	for _, p := range strings.Split(traceRuleIDPaths, ", ") {
+		for _, id := range extract(doc, strings.Trim(p, "'")) {
+			got = append(got, uint(id.(float64)))
+		}
+	}
+	slices.Sort(got)
+	var want []uint
+	for id := uint(1); id <= rules.MaxCompositeDepth+1; id++ {
+		want = append(want, id)
+	}
+	want = append(want, 100)
+	if !slices.Equal(got, want) {
+		t.Errorf("rule IDs reached = %v, want %v", got, want)
+	}
+}
@@ -1,34 +1,178 @@
 package repository
 
 import (
+	"time"
+
+	"github.com/warleon/ms4-compliance-service/internal/dto"
+	"github.com/warleon/ms4-compliance-service/internal/money"
 	"github.com/warleon/ms4-compliance-service/internal/repository/rules"
 	"gorm.io/gorm"
 )
 
-// AuditLog stores decisions for regulatory reporting.
+// AuditLog stores decisions for regulatory reporting. Transaction is the
+// evaluated input and Trace the per-rule evaluation that led to Decision.
+// Hash covers the entry's content and PrevHash, the hash of the entry before
+// it, so any change to recorded history breaks the chain.
 type AuditLog struct {
-	gorm.Model
-	TransactionID string         `gorm:"index" json:"transactionId"`
-	CustomerID    string         `gorm:"index" json:"customerId"`
-	DecisionID    uint           `json:"decisionId"`
-	Decision      rules.Decision `json:"decision"`
+	gorm.Model    `swaggerignore:"true"`
+	TransactionID string             `gorm:"index" json:"transactionId"`
+	CustomerID    string             `gorm:"index" json:"customerId"`
+	DecisionID    uint               `json:"decisionId"`
+	Decision      rules.Decision     `json:"decision"`
+	Transaction   dto.Transaction    `gorm:"serializer:json;type:json" json:"transaction"`
+	Trace         []rules.RuleResult `gorm:"serializer:json;type:json" json:"trace"`
+	PrevHash      string             `gorm:"size:64" json:"prevHash"`
+	Hash          string             `gorm:"size:64;index" json:"hash"`
+}
+
+// TransactionRecord keeps transactions that were not rejected so velocity
+// rules can look back over a time window.
+type TransactionRecord struct {
+	gorm.Model    `swaggerignore:"true"`
+	TransactionID string       `gorm:"index" json:"transactionId"`
+	CustomerID    string       `gorm:"index" json:"customerId"`
+	FromAcc       string       `gorm:"index" json:"fromAcc"`
+	ToAcc         string       `gorm:"index" json:"toAcc"`
+	Amount        money.Amount `json:"amount" swaggertype:"number"`
+	Currency      string       `json:"currency"`
+}
+
+// TransactionFilter narrows the recorded transactions aggregated by TransactionStats.
+type TransactionFilter struct {
+	Key       rules.VelocityKey
+	Value     string
+	Currency  string // empty matches every currency
+	Since     time.Time
+	Until     time.Time     // exclusive; zero means no upper bound
+	MinAmount *money.Amount // inclusive
+	MaxAmount *money.Amount // exclusive
+}
+
+// AuditFilter narrows an audit query. Zero values are ignored. Results are
+// ordered newest first; Cursor is the ID of the last entry of the previous
+// page, so only entries with a lower ID are returned.
+type AuditFilter struct {
+	CustomerID    string
+	TransactionID string
+	Status        rules.DecisionStatus
+	RuleID        uint // entries whose trace includes the rule, at any depth
+	Shadow        bool // only entries with shadow rule results
+	From          *time.Time
+	To            *time.Time
+	Cursor        uint
+	Size          int
+}
+
+// RuleFilter selects rules for listing. Period, when set, keeps the rules
+// that are upcoming, active or expired at At, judged by their effective dates.
+type RuleFilter struct {
+	Period rules.RulePeriod
+	At     time.Time
+	Size   int
+	Offset int
 }
 
 // Repository defines DB operations needed by the service.
 type Repository interface {
+	// WithTx runs fn inside a database transaction. The repository passed to fn
+	// is bound to that transaction; returning an error rolls everything back.
+	WithTx(fn func(repo Repository) error) error
 	CreateRule(r *rules.Rule) error
 	ReadRule(id uint) (*rules.Rule, error)
-	ReadRules(size int, offset int) ([]rules.Rule, error)
-	UpdateRule(r *rules.Rule) error
+	ReadRules(f RuleFilter) ([]rules.Rule, error)
+	// SaveRule writes every field of r, empty ones included.
+	SaveRule(r *rules.Rule) error
+	// LockRule reads a rule and locks it until the transaction ends.
+	LockRule(id uint) (*rules.Rule, error)
+	CreateRuleVersion(v *RuleVersion) error
+	// ReadRuleVersions returns the versions of a rule, newest first.
+	ReadRuleVersions(ruleID uint) ([]RuleVersion, error)
+	ReadRuleVersion(ruleID, version uint) (*RuleVersion, error)
+	// ClaimIdempotencyKey inserts k unless its transaction ID is taken. When it
+	// is, the existing key is returned, locked until the transaction ends; a
+	// concurrent claim waits for the first one to commit or roll back.
+	ClaimIdempotencyKey(k *IdempotencyKey) (*IdempotencyKey, error)
+	SaveIdempotencyKey(k *IdempotencyKey) error
+	// DeleteIdempotencyKeys removes keys created before t.
+	DeleteIdempotencyKeys(before time.Time) (int64, error)
 	DeleteRule(id uint) error
+	// CreateAudit appends a to the audit hash chain.
 	CreateAudit(a *AuditLog) error
 	ReadAuidits(size int, offset int) ([]AuditLog, error)
+	ReadAudit(id uint) (*AuditLog, error)
+	// FindAudits returns audit entries matching the filter, newest first.
+	FindAudits(f AuditFilter) ([]AuditLog, error)
+	// ScanAudits returns up to size audit entries with ID above afterID in ID order,
+	// including soft-deleted ones, for verifying the hash chain.
+	ScanAudits(afterID uint, size int) ([]AuditLog, error)
+	ReadAuditChainHead() (*AuditChainHead, error)
 	// FindRulesByType returns rules filtered by their Type field (e.g. "amount_threshold").
 	FindRulesByType(ruleType string) ([]rules.Rule, error)
+	// FindRules returns every rule, in ID order.
+	FindRules() ([]rules.Rule, error)
 	// IsAccountSanctioned checks whether an account identifier exists in the sanctions table.
 	IsAccountSanctioned(accID string) (bool, error)
+	// ListSanctionedAccounts returns every account identifier in the sanctions table.
+	ListSanctionedAccounts() ([]string, error)
+	// FindSanctionedParties returns every sanctions entry that carries a party
+	// name, with its names normalized for screening.
+	FindSanctionedParties() ([]rules.Sanction, error)
+	CreateSanction(s *rules.Sanction) error
+	ReadSanction(id uint) (*rules.Sanction, error)
+	ReadSanctions(size int, offset int) ([]rules.Sanction, error)
+	DeleteSanction(id uint) error
+	// FindSanctionedAccounts returns which of the given account identifiers are
+	// already present in the sanctions table.
+	FindSanctionedAccounts(accIDs []string) ([]string, error)
+	// CreateSanctions inserts sanctions in batches; callers wrap it in WithTx to
+	// make a bulk upload all-or-nothing.
+	CreateSanctions(s []rules.Sanction) error
+	// DeleteSanctionsBySource removes every entry imported from the given list.
+	DeleteSanctionsBySource(source rules.SanctionSource) (int64, error)
+	CreateCase(c *Case) error
+	// ReadCase returns a case with its events, oldest first.
+	ReadCase(id uint) (*Case, error)
+	// LockCase returns a case locked for update until the surrounding
+	// transaction ends. It must be called inside WithTx.
+	LockCase(id uint) (*Case, error)
+	FindCases(f CaseFilter) ([]Case, error)
+	UpdateCase(c *Case) error
+	CreateAuditEvent(e *AuditEvent) error
+	CreateTransaction(t *TransactionRecord) error
+	CreateJob(j *Job) error
+	// CreateJobItems inserts items in batches.
+	CreateJobItems(items []JobItem) error
+	ReadJob(id uint) (*Job, error)
+	UpdateJob(j *Job) error
+	// ClaimJob hands owner the oldest queued or running job that owner
+	// already holds or whose lease has expired, leased for lease. It returns
+	// nil when there is none. Concurrent claims never get the same job.
+	ClaimJob(owner string, lease time.Duration) (*Job, error)
+	// HoldJob locks j until the transaction ends and extends its lease by
+	// lease, or returns ErrJobLeaseLost if owner no longer holds it.
+	HoldJob(j *Job, owner string, lease time.Duration) error
+	// PendingJobItems returns up to size items of a job without a result, in order.
+	PendingJobItems(jobID uint, size int) ([]JobItem, error)
+	// FinishJobItem stores the result of it and marks it done, or returns
+	// ErrJobItemDone if it already was.
+	FinishJobItem(it *JobItem) error
+	// JobProgress counts the done and the failed items of a job.
+	JobProgress(jobID uint) (processed, failed int, err error)
+	// ReadJobResults returns up to size finished items of a job after seq, in order.
+	ReadJobResults(jobID uint, afterSeq, size int) ([]JobItem, error)
+	// TransactionStats returns how many recorded transactions match the filter,
+	// and the sum of their amounts.
+	TransactionStats(f TransactionFilter) (int64, money.Amount, error)
 }
 
 var RepositoryTables = []any{
 	&AuditLog{},
+	&AuditChainHead{},
+	&TransactionRecord{},
+	&Case{},
+	&AuditEvent{},
+	&RuleVersion{},
+	&IdempotencyKey{},
+	&Job{},
+	&JobItem{},
 }
@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/warleon/ms4-compliance-service/internal/repository/rules"
+)
+
+// RuleVersion is an immutable snapshot of a rule. One is written each time a
+// rule is created, updated, rolled back or deleted; audit traces name the
+// version that produced each rule result. The version written on delete has
+// Deleted set and holds the rule as it was last in effect.
+type RuleVersion struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	RuleID    uint       `gorm:"uniqueIndex:idx_rule_version" json:"ruleId"`
+	Version   uint       `gorm:"uniqueIndex:idx_rule_version" json:"version"`
+	Snapshot  rules.Rule `gorm:"serializer:json;type:json" json:"snapshot"`
+	Deleted   bool       `json:"deleted,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// NewRuleVersion snapshots r at its current version.
+func NewRuleVersion(r *rules.Rule) *RuleVersion {
+	return &RuleVersion{RuleID: r.ID, Version: r.Version, Snapshot: *r}
+}
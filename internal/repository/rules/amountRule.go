@@ -1,18 +1,32 @@
 package rules
 
-import "github.com/warleon/ms4-compliance-service/internal/dto"
+import (
+	"github.com/warleon/ms4-compliance-service/internal/dto"
+	"github.com/warleon/ms4-compliance-service/internal/money"
+)
 
 type AmountThresholdRule struct {
 	RuleBase
-	Threshold float64
+	Threshold money.Amount
+}
+
+func newAmountThresholdRule(r *Rule, tx dto.Transaction, env Env) (ComplianceRule, error) {
+	if r.Threshold == nil {
+		return nil, Skip("missing threshold")
+	}
+	return &AmountThresholdRule{RuleBase: r.RuleBase, Threshold: *r.Threshold}, nil
 }
 
 func (r *AmountThresholdRule) Validate(tx dto.Transaction) Decision {
-	if tx.Amount > r.Threshold {
-		return Decision{
-			Approved: false,
-			Reason:   "Transaction exceeds threshold",
-		}
+	if tx.Amount.Cmp(r.Threshold) > 0 {
+		return r.Fail(StatusReject, "Transaction exceeds threshold")
+	}
+	return Approve()
+}
+
+func (r *AmountThresholdRule) Inputs(tx dto.Transaction, dec Decision) map[string]any {
+	return map[string]any{
+		"amount":    tx.Amount,
+		"threshold": r.Threshold,
 	}
-	return Decision{Approved: true, Reason: "OK"}
 }
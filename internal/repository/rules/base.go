@@ -1,7 +1,12 @@
 package rules
 
 import (
+	"errors"
+	"fmt"
+	"time"
+
 	"github.com/warleon/ms4-compliance-service/internal/dto"
+	"github.com/warleon/ms4-compliance-service/internal/money"
 	"gorm.io/gorm"
 )
 
@@ -10,6 +15,21 @@ type RuleType string
 const (
 	RuleTypeAmountThreshold RuleType = "amount_threshold"
 	RuleTypeSanctionsList   RuleType = "sanctions_list"
+	RuleTypeVelocity        RuleType = "velocity"
+	RuleTypeStructuring     RuleType = "structuring"
+	RuleTypeNameScreening   RuleType = "name_screening"
+	RuleTypeExpression      RuleType = "expression"
+	RuleTypeComposite       RuleType = "composite"
+)
+
+// RuleStatus says whether a rule's results count.
+type RuleStatus string
+
+const (
+	// RuleActive rules take part in the decision. An empty status is active.
+	RuleActive RuleStatus = "active"
+	// RuleShadow rules are evaluated and traced but never change the decision.
+	RuleShadow RuleStatus = "shadow"
 )
 
 // Rule represents a compliance rule stored in DB.
@@ -17,17 +37,75 @@ type RuleBase struct {
 	gorm.Model  `swaggerignore:"true"`
 	Name        string   `gorm:"size:255;not null" json:"name"`
 	Description string   `gorm:"type:text" json:"description"`
-	Type        RuleType `gorm:"type:enum('amount_threshold','sanctions_list');not null"`
+	Type        RuleType `gorm:"type:enum('amount_threshold','sanctions_list','velocity','structuring','name_screening','expression','composite');not null"`
 	Account     string   `gorm:"index"`
+	// Version counts the changes to the rule, starting at 1. Every version is
+	// kept as a RuleVersion snapshot.
+	Version uint `json:"version"`
+	// Status is active (the default) or shadow.
+	Status RuleStatus `gorm:"size:16;index" json:"status,omitempty"`
+	// Outcome overrides the status a failing rule produces (manual_review or
+	// reject). Empty keeps the rule type's default.
+	Outcome DecisionStatus `gorm:"size:16" json:"outcome,omitempty"`
+	// Weight is the number of risk score points (0-100) the rule adds when it
+	// fails. Empty uses the default for the failing status.
+	Weight *float64 `json:"weight,omitempty"`
+	// Scope limits the rule to one account, customer or customer segment,
+	// named by ScopeValue. Empty is global, or account scoped when Account is
+	// set. Group names the rules a scoped rule overrides; by default it
+	// overrides less specific rules of the same type.
+	Scope      RuleScope `gorm:"size:16" json:"scope,omitempty"`
+	ScopeValue string    `gorm:"size:100;index" json:"scopeValue,omitempty"`
+	Group      string    `gorm:"size:64" json:"group,omitempty"`
+	// EffectiveFrom and EffectiveTo bound when the rule is in effect; either
+	// may be empty. Schedule optionally narrows that to recurring windows.
+	EffectiveFrom *time.Time   `gorm:"index" json:"effectiveFrom,omitempty"`
+	EffectiveTo   *time.Time   `gorm:"index" json:"effectiveTo,omitempty"`
+	Schedule      []TimeWindow `gorm:"serializer:json;type:json" json:"schedule,omitempty"`
 }
 
-// ComplianceRule is the interface each rule implements.
+// Shadow reports whether the rule runs in shadow mode.
+func (r RuleBase) Shadow() bool {
+	return r.Status == RuleShadow
+}
+
+// Fail returns the decision for a rule that did not pass: the rule's Outcome
+// when set, otherwise def.
+func (r RuleBase) Fail(def DecisionStatus, reason string) Decision {
+	if r.Outcome != "" {
+		def = r.Outcome
+	}
+	return NewDecision(def, reason)
+}
+
+// ComplianceRule is the interface each rule implements. Rules are built for
+// one transaction by the Factory registered for their type.
 type ComplianceRule interface {
 	Validate(tx dto.Transaction) Decision
+	// Inputs returns the values the rule looked at, for the decision trace.
+	Inputs(tx dto.Transaction, dec Decision) map[string]any
 }
 type RuleExtras struct {
-	Threshold *float64
-	db        *gorm.DB `swaggerignore:"true"`
+	Threshold *money.Amount `swaggertype:"number"`
+	// Velocity settings: Window is a duration string such as "1h" or "24h".
+	Window    *string       `gorm:"size:32" json:"window,omitempty"`
+	MaxCount  *int64        `json:"maxCount,omitempty"`
+	MaxAmount *money.Amount `json:"maxAmount,omitempty" swaggertype:"number"`
+	KeyField  *VelocityKey  `gorm:"size:32" json:"keyField,omitempty"`
+	// Structuring settings: Tolerance is the percentage below Threshold that
+	// counts as "just under", MinCount how many such transactions raise a flag.
+	Tolerance *float64 `json:"tolerance,omitempty"`
+	MinCount  *int64   `json:"minCount,omitempty"`
+	// Name screening settings: MetadataKey names the transaction metadata entry
+	// holding the party name, MatchThreshold the minimum score (0-1) for a match.
+	MetadataKey    *string  `gorm:"size:64" json:"metadataKey,omitempty"`
+	MatchThreshold *float64 `json:"matchThreshold,omitempty"`
+	// Expression settings: a condition over the transaction, such as
+	// `amount > 5000 && metadata.channel == "web"`, that fails the rule when true.
+	Expression *string `gorm:"type:text" json:"expression,omitempty"`
+	// Composite settings: Operator combines the rules listed in Children.
+	Operator *CompositeOp `gorm:"size:8" json:"operator,omitempty"`
+	Children []uint       `gorm:"serializer:json;type:json" json:"children,omitempty"`
 }
 
 type Rule struct {
@@ -40,3 +118,40 @@ var RuleTables = []any{
 	&Sanction{},
 	&Decision{},
 }
+
+// Check reports configuration errors that would make the rule unusable.
+func (r *Rule) Check() error {
+	if r.Outcome != "" && r.Outcome != StatusManualReview && r.Outcome != StatusReject {
+		return errors.New("outcome must be manual_review or reject")
+	}
+	if r.Status != "" && r.Status != RuleActive && r.Status != RuleShadow {
+		return errors.New("status must be active or shadow")
+	}
+	if r.Weight != nil && (*r.Weight < 0 || *r.Weight > MaxRiskScore) {
+		return errors.New("weight must be between 0 and 100")
+	}
+	if r.Tolerance != nil && (*r.Tolerance <= 0 || *r.Tolerance > MaxTolerance) {
+		return fmt.Errorf("tolerance must be above 0 and at most %d percent", MaxTolerance)
+	}
+	if r.MatchThreshold != nil && (*r.MatchThreshold <= 0 || *r.MatchThreshold > 1) {
+		return errors.New("matchThreshold must be above 0 and at most 1")
+	}
+	if err := r.checkScope(); err != nil {
+		return err
+	}
+	if err := r.checkSchedule(); err != nil {
+		return err
+	}
+	if r.Type == RuleTypeExpression {
+		if r.Expression == nil || *r.Expression == "" {
+			return errors.New("expression rules need an expression")
+		}
+		if _, err := CompileExpression(*r.Expression); err != nil {
+			return fmt.Errorf("expression: %w", err)
+		}
+	}
+	if r.Type == RuleTypeComposite {
+		return r.checkComposite()
+	}
+	return nil
+}
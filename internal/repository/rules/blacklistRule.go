@@ -1,32 +1,80 @@
 package rules
 
 import (
+	"time"
+
 	"github.com/warleon/ms4-compliance-service/internal/dto"
 	"gorm.io/gorm"
 )
 
+// SanctionSource identifies the list a sanctions entry came from.
+type SanctionSource string
+
+const (
+	SanctionSourceManual SanctionSource = "manual"
+	SanctionSourceOFAC   SanctionSource = "ofac"
+	SanctionSourceUN     SanctionSource = "un"
+	SanctionSourceEU     SanctionSource = "eu"
+)
+
+// Sanction is a sanctioned party. Entries added through the API carry an
+// account identifier; entries imported from official lists describe a named
+// party and usually have no AccID.
 type Sanction struct {
 	gorm.Model `swaggerignore:"true"`
-	AccID      string `gorm:"size:100;index"` // account/customer identifier
+	AccID      string         `gorm:"size:100;index" json:"accId"` // account/customer identifier
+	Name       string         `gorm:"size:255;index" json:"name,omitempty"`
+	Aliases    []string       `gorm:"serializer:json;type:json" json:"aliases,omitempty"`
+	PartyType  string         `gorm:"size:32" json:"partyType,omitempty"` // individual, entity, vessel...
+	Country    string         `gorm:"size:100" json:"country,omitempty"`
+	Program    string         `gorm:"size:255" json:"program,omitempty"`
+	Source     SanctionSource `gorm:"size:16;index" json:"source,omitempty"`
+	ExternalID string         `gorm:"size:64;index" json:"externalId,omitempty"` // identifier within the source list
+	ListedAt   *time.Time     `json:"listedAt,omitempty"`
+	// Normalized holds Name and then Aliases passed through
+	// screening.Normalize, so screening does not redo it for every
+	// transaction. It is filled in by NormalizeNames and not stored.
+	Normalized []string `gorm:"-" json:"-"`
 }
 
+// BlacklistRule rejects transactions from or to a sanctioned account.
+// FromSanctioned and ToSanctioned hold the sanctions lookups for the
+// transaction's accounts.
 type BlacklistRule struct {
 	RuleBase
-	db *gorm.DB
+	FromSanctioned bool
+	ToSanctioned   bool
 }
 
-func (r *BlacklistRule) Validate(tx dto.Transaction) Decision {
-	var entry Sanction
-
-	// Fast lookup using indexed column
-	err := r.db.Where(&Sanction{AccID: tx.FromAcc}).Or(&Sanction{AccID: tx.ToAcc}).First(&entry).Error
+func newBlacklistRule(r *Rule, tx dto.Transaction, env Env) (ComplianceRule, error) {
+	br := &BlacklistRule{RuleBase: r.RuleBase}
+	var err error
+	if br.FromSanctioned, err = env.IsAccountSanctioned(tx.FromAcc); err != nil {
+		return nil, err
+	}
+	if br.ToSanctioned, err = env.IsAccountSanctioned(tx.ToAcc); err != nil {
+		return nil, err
+	}
+	return br, nil
+}
 
-	if err == nil {
-		return Decision{
-			Approved: false,
-			Reason:   "Account is blacklisted",
-		}
+func (r *BlacklistRule) Validate(tx dto.Transaction) Decision {
+	switch {
+	case r.FromSanctioned && r.ToSanctioned:
+		return r.Fail(StatusReject, "From and to accounts are sanctioned")
+	case r.FromSanctioned:
+		return r.Fail(StatusReject, "From account is sanctioned")
+	case r.ToSanctioned:
+		return r.Fail(StatusReject, "To account is sanctioned")
 	}
+	return Approve()
+}
 
-	return Decision{Approved: true, Reason: "OK"}
+func (r *BlacklistRule) Inputs(tx dto.Transaction, dec Decision) map[string]any {
+	return map[string]any{
+		"fromAcc":        tx.FromAcc,
+		"toAcc":          tx.ToAcc,
+		"fromSanctioned": r.FromSanctioned,
+		"toSanctioned":   r.ToSanctioned,
+	}
 }
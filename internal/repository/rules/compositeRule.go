@@ -0,0 +1,154 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/warleon/ms4-compliance-service/internal/dto"
+)
+
+// CompositeOp combines the results of a composite rule's children.
+type CompositeOp string
+
+const (
+	// CompositeAnd fails when every child fails.
+	CompositeAnd CompositeOp = "and"
+	// CompositeOr fails when any child fails.
+	CompositeOr CompositeOp = "or"
+	// CompositeNot fails when its single child passes.
+	CompositeNot CompositeOp = "not"
+)
+
+// Valid reports whether op is a known operator.
+func (op CompositeOp) Valid() bool {
+	return op == CompositeAnd || op == CompositeOr || op == CompositeNot
+}
+
+// MaxCompositeDepth bounds how deeply composite rules may nest.
+const MaxCompositeDepth = 8
+
+// CompositeRule combines the results of other rules, which may themselves be
+// composite. Results holds how each child evaluated, in the order of Children.
+type CompositeRule struct {
+	RuleBase
+	Op      CompositeOp
+	Results []RuleResult
+}
+
+// nestedEnv records how deeply composite rules are nested.
+type nestedEnv struct {
+	Env
+	depth int
+}
+
+// newCompositeRule evaluates every child of r. A child that is not in effect
+// at the transaction's time or does not apply to it is left out, as it would
+// be on its own. A child that is missing or cannot be evaluated skips the
+// whole rule, since its result would be a guess; the returned rule still
+// carries the child results for the trace.
+func newCompositeRule(r *Rule, tx dto.Transaction, env Env) (ComplianceRule, error) {
+	if err := r.checkComposite(); err != nil {
+		return nil, Skip(err.Error())
+	}
+	child := nestedEnv{Env: env, depth: 1}
+	if n, ok := env.(nestedEnv); ok {
+		child = nestedEnv{Env: n.Env, depth: n.depth + 1}
+	}
+	if child.depth > MaxCompositeDepth {
+		return nil, Skip(fmt.Sprintf("composite rules nested more than %d levels", MaxCompositeDepth))
+	}
+	cr := &CompositeRule{RuleBase: r.RuleBase, Op: *r.Operator}
+	var skipped error
+	for _, id := range r.Children {
+		c, err := env.Rule(id)
+		if err != nil {
+			return nil, err
+		}
+		var res RuleResult
+		switch {
+		case c == nil:
+			res = RuleResult{RuleID: id, Skipped: true, Status: StatusApprove, Reason: "rule not found"}
+		case !c.ActiveAt(tx.Timestamp):
+			cr.Results = append(cr.Results, SkippedRuleResult(c.RuleBase, "rule not in effect"))
+			continue
+		case !c.AppliesTo(tx):
+			cr.Results = append(cr.Results, SkippedRuleResult(c.RuleBase, "rule does not apply to the transaction"))
+			continue
+		default:
+			if _, res, err = Evaluate(c, tx, child); err != nil {
+				return nil, err
+			}
+			// only the composite itself counts towards the risk score
+			res.Score = 0
+		}
+		if res.Skipped && skipped == nil {
+			skipped = Skip(fmt.Sprintf("child rule %d could not be evaluated", id))
+		}
+		cr.Results = append(cr.Results, res)
+	}
+	return cr, skipped
+}
+
+// checkComposite reports configuration errors in a composite rule.
+func (r *Rule) checkComposite() error {
+	if r.Operator == nil || !r.Operator.Valid() {
+		return errors.New("composite rules need an operator: and, or or not")
+	}
+	if len(r.Children) == 0 {
+		return errors.New("composite rules need children")
+	}
+	if *r.Operator == CompositeNot && len(r.Children) != 1 {
+		return errors.New("a not rule takes exactly one child")
+	}
+	seen := make(map[uint]bool, len(r.Children))
+	for _, id := range r.Children {
+		if id == 0 || seen[id] {
+			return fmt.Errorf("invalid or repeated child rule %d", id)
+		}
+		if id == r.ID {
+			return errors.New("a composite rule cannot contain itself")
+		}
+		seen[id] = true
+	}
+	return nil
+}
+
+// Validate combines the results of the children that were evaluated; the
+// skipped ones are those left out. With no child left, the rule passes.
+func (r *CompositeRule) Validate(tx dto.Transaction) Decision {
+	evaluated, failed := 0, 0
+	var reasons []string
+	for _, c := range r.Results {
+		if c.Skipped {
+			continue
+		}
+		evaluated++
+		if c.Status != StatusApprove {
+			failed++
+			reasons = append(reasons, c.Reason)
+		}
+	}
+	if evaluated == 0 {
+		return Approve()
+	}
+	switch r.Op {
+	case CompositeAnd:
+		if failed == evaluated {
+			return r.Fail(StatusReject, "All conditions matched: "+strings.Join(reasons, "; "))
+		}
+	case CompositeOr:
+		if failed > 0 {
+			return r.Fail(StatusReject, "Condition matched: "+strings.Join(reasons, "; "))
+		}
+	case CompositeNot:
+		if failed == 0 {
+			return r.Fail(StatusReject, "Condition did not match")
+		}
+	}
+	return Approve()
+}
+
+func (r *CompositeRule) Inputs(tx dto.Transaction, dec Decision) map[string]any {
+	return map[string]any{"operator": r.Op}
+}
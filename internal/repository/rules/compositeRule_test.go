@@ -0,0 +1,134 @@
+package rules
+
+import (
+	"testing"
+	"time"
+
+	"github.com/warleon/ms4-compliance-service/internal/dto"
+	"github.com/warleon/ms4-compliance-service/internal/money"
+)
+
+// ruleSetEnv serves rules by ID.
+type ruleSetEnv struct {
+	Env
+	rules map[uint]*Rule
+}
+
+func (e ruleSetEnv) Rule(id uint) (*Rule, error) { return e.rules[id], nil }
+
+func thresholdRule(id uint, threshold string) *Rule {
+	t := money.MustParse(threshold)
+	r := &Rule{RuleExtras: RuleExtras{Threshold: &t}}
+	r.ID, r.Type = id, RuleTypeAmountThreshold
+	return r
+}
+
+func composite(id uint, op CompositeOp, children ...uint) *Rule {
+	r := &Rule{RuleExtras: RuleExtras{Operator: &op, Children: children}}
+	r.ID, r.Type = id, RuleTypeComposite
+	return r
+}
+
+func TestCompositeRule(t *testing.T) {
+	now := time.Now()
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+	expired := thresholdRule(4, "10")
+	expired.EffectiveTo = &past
+	upcoming := thresholdRule(5, "10")
+	upcoming.EffectiveFrom = &future
+	otherAccount := thresholdRule(6, "10")
+	otherAccount.Scope, otherAccount.ScopeValue = ScopeAccount, "OTHER"
+	ownAccount := thresholdRule(7, "10")
+	ownAccount.Scope, ownAccount.ScopeValue = ScopeAccount, "A"
+	env := ruleSetEnv{rules: map[uint]*Rule{
+		1: thresholdRule(1, "10"),   // fails
+		2: thresholdRule(2, "1000"), // passes
+		3: thresholdRule(3, "50"),   // fails
+		4: expired, 5: upcoming, 6: otherAccount, 7: ownAccount,
+		8: composite(8, CompositeOr, 1, 2),
+	}}
+	tx := dto.Transaction{FromAcc: "A", ToAcc: "B", Amount: money.MustParse("100"), Timestamp: now}
+
+	for _, tc := range []struct {
+		name    string
+		rule    *Rule
+		want    DecisionStatus
+		skipped bool
+	}{
+		{"and, all fail", composite(10, CompositeAnd, 1, 3), StatusReject, false},
+		{"and, one passes", composite(10, CompositeAnd, 1, 2), StatusApprove, false},
+		{"or, one fails", composite(10, CompositeOr, 2, 1), StatusReject, false},
+		{"or, all pass", composite(10, CompositeOr, 2), StatusApprove, false},
+		{"not, child passes", composite(10, CompositeNot, 2), StatusReject, false},
+		{"not, child fails", composite(10, CompositeNot, 1), StatusApprove, false},
+		{"nested", composite(10, CompositeAnd, 8, 3), StatusReject, false},
+		// children out of effect or scope are left out
+		{"and without an expired child", composite(10, CompositeAnd, 1, 4), StatusReject, false},
+		{"or without an upcoming child", composite(10, CompositeOr, 2, 5), StatusApprove, false},
+		{"or without an out of scope child", composite(10, CompositeOr, 2, 6), StatusApprove, false},
+		{"or with an in scope child", composite(10, CompositeOr, 2, 7), StatusReject, false},
+		{"not with no child left", composite(10, CompositeNot, 4), StatusApprove, false},
+		{"missing child", composite(10, CompositeOr, 1, 99), StatusApprove, true},
+		{"invalid", composite(10, CompositeNot, 1, 2), StatusApprove, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dec, res, err := Evaluate(tc.rule, tx, env)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if dec.Status != tc.want || res.Skipped != tc.skipped {
+				t.Fatalf("decision = %s, skipped %v (%s), want %s, skipped %v", dec.Status, res.Skipped, res.Reason, tc.want, tc.skipped)
+			}
+			if !tc.skipped && len(res.Children) != len(tc.rule.Children) {
+				t.Fatalf("trace has %d children, want %d", len(res.Children), len(tc.rule.Children))
+			}
+			for _, c := range res.Children {
+				if c.Score != 0 {
+					t.Fatalf("child %d scored %v", c.RuleID, c.Score)
+				}
+			}
+		})
+	}
+
+	_, res, _ := Evaluate(composite(10, CompositeAnd, 1, 4, 6), tx, env)
+	if r := res.Children[1]; !r.Skipped || r.Reason != "rule not in effect" {
+		t.Fatalf("expired child = %+v", r)
+	}
+	if r := res.Children[2]; !r.Skipped || r.Reason != "rule does not apply to the transaction" {
+		t.Fatalf("out of scope child = %+v", r)
+	}
+}
+
+func TestCompositeDepth(t *testing.T) {
+	tx := dto.Transaction{Amount: money.MustParse("100"), Timestamp: time.Now()}
+	// chain returns the top of n composites nested in each other around a
+	// failing rule
+	chain := func(n int) (*Rule, ruleSetEnv) {
+		env := ruleSetEnv{rules: map[uint]*Rule{1: thresholdRule(1, "10")}}
+		child := uint(1)
+		for i := range n {
+			id := uint(100 + i)
+			env.rules[id] = composite(id, CompositeOr, child)
+			child = id
+		}
+		return env.rules[child], env
+	}
+
+	top, env := chain(MaxCompositeDepth)
+	if dec, res, err := Evaluate(top, tx, env); err != nil || dec.Status != StatusReject || res.Skipped {
+		t.Fatalf("%d levels: %s, skipped %v, %v; want reject", MaxCompositeDepth, dec.Status, res.Skipped, err)
+	}
+	top, env = chain(MaxCompositeDepth + 1)
+	if dec, res, err := Evaluate(top, tx, env); err != nil || dec.Status != StatusApprove || !res.Skipped {
+		t.Fatalf("%d levels: %s, skipped %v, %v; want skipped", MaxCompositeDepth+1, dec.Status, res.Skipped, err)
+	}
+
+	// a cycle that slipped past the checks on save ends at the depth limit
+	cycle := ruleSetEnv{rules: map[uint]*Rule{
+		1: composite(1, CompositeOr, 2),
+		2: composite(2, CompositeOr, 1),
+	}}
+	if dec, res, err := Evaluate(cycle.rules[1], tx, cycle); err != nil || dec.Status != StatusApprove || !res.Skipped {
+		t.Fatalf("cycle: %s, skipped %v, %v; want skipped", dec.Status, res.Skipped, err)
+	}
+}
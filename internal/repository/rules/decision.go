@@ -2,8 +2,61 @@ package rules
 
 import "gorm.io/gorm"
 
+// DecisionStatus is the outcome of a validation.
+type DecisionStatus string
+
+const (
+	StatusApprove      DecisionStatus = "approve"
+	StatusManualReview DecisionStatus = "manual_review"
+	StatusReject       DecisionStatus = "reject"
+)
+
+// severity orders statuses for combining decisions: reject beats review,
+// review beats approve.
+func (s DecisionStatus) severity() int {
+	switch s {
+	case StatusReject:
+		return 2
+	case StatusManualReview:
+		return 1
+	}
+	return 0
+}
+
+// Outranks reports whether s takes precedence over other.
+func (s DecisionStatus) Outranks(other DecisionStatus) bool {
+	return s.severity() > other.severity()
+}
+
+// Valid reports whether s is a known status.
+func (s DecisionStatus) Valid() bool {
+	switch s {
+	case StatusApprove, StatusManualReview, StatusReject:
+		return true
+	}
+	return false
+}
+
+// Decision is the result of validating a transaction. Approved is true only
+// when Status is approve; it is kept for callers that predate Status.
 type Decision struct {
 	gorm.Model `swaggerignore:"true"`
 	Approved   bool
+	Status     DecisionStatus `gorm:"size:16;index"`
 	Reason     string
+	RiskScore  float64      // 0-100, the sum of the rule contributions in Results
+	Matches    []NameMatch  `gorm:"serializer:json;type:json"` // name screening candidates, best first
+	CaseID     uint         `gorm:"-" json:",omitempty"`       // review case opened for a manual_review decision
+	Results    []RuleResult `gorm:"-" json:",omitempty"`       // outcome of each rule evaluated, in order
+	Replayed   bool         `gorm:"-" json:",omitempty"`       // stored decision returned for a repeated transaction ID
+}
+
+// Approve returns an approving decision.
+func Approve() Decision {
+	return Decision{Approved: true, Status: StatusApprove, Reason: "OK"}
+}
+
+// NewDecision returns a decision with the given status and reason.
+func NewDecision(status DecisionStatus, reason string) Decision {
+	return Decision{Approved: status == StatusApprove, Status: status, Reason: reason}
 }
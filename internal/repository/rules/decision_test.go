@@ -0,0 +1,67 @@
+package rules
+
+import "testing"
+
+func TestStatusPrecedence(t *testing.T) {
+	order := []DecisionStatus{StatusApprove, StatusManualReview, StatusReject}
+	for i, s := range order {
+		if !s.Valid() {
+			t.Errorf("%s is not valid", s)
+		}
+		for j, other := range order {
+			if got := s.Outranks(other); got != (i > j) {
+				t.Errorf("%s.Outranks(%s) = %v, want %v", s, other, got, i > j)
+			}
+		}
+	}
+	for _, s := range []DecisionStatus{"", "approved", "REJECT"} {
+		if s.Valid() {
+			t.Errorf("%q is valid", s)
+		}
+	}
+}
+
+func TestNewDecision(t *testing.T) {
+	for _, s := range []DecisionStatus{StatusApprove, StatusManualReview, StatusReject} {
+		if d := NewDecision(s, "x"); d.Status != s || d.Approved != (s == StatusApprove) {
+			t.Errorf("NewDecision(%s) = %+v", s, d)
+		}
+	}
+	if d := Approve(); d.Status != StatusApprove || !d.Approved {
+		t.Errorf("Approve() = %+v", d)
+	}
+}
+
+func TestFailUsesOutcome(t *testing.T) {
+	for _, tc := range []struct {
+		outcome, def, want DecisionStatus
+	}{
+		{"", StatusReject, StatusReject},
+		{"", StatusManualReview, StatusManualReview},
+		{StatusManualReview, StatusReject, StatusManualReview},
+		{StatusReject, StatusManualReview, StatusReject},
+	} {
+		r := RuleBase{Outcome: tc.outcome}
+		if d := r.Fail(tc.def, "why"); d.Status != tc.want || d.Reason != "why" || d.Approved {
+			t.Errorf("outcome %q, default %s: Fail = %+v, want %s", tc.outcome, tc.def, d, tc.want)
+		}
+	}
+}
+
+func TestCheckOutcome(t *testing.T) {
+	for _, tc := range []struct {
+		outcome DecisionStatus
+		ok      bool
+	}{
+		{"", true},
+		{StatusManualReview, true},
+		{StatusReject, true},
+		{StatusApprove, false},
+		{"block", false},
+	} {
+		r := &Rule{RuleBase: RuleBase{Type: RuleTypeSanctionsList, Outcome: tc.outcome}}
+		if err := r.Check(); (err == nil) != tc.ok {
+			t.Errorf("outcome %q: Check = %v, want ok %v", tc.outcome, err, tc.ok)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package rules
+
+import (
+	"github.com/warleon/ms4-compliance-service/internal/dto"
+	"github.com/warleon/ms4-compliance-service/internal/expr"
+)
+
+// expressions caches compiled rule expressions by source.
+var expressions expr.Cache
+
+// CompileExpression returns the compiled program for src, reusing earlier
+// compilations of the same source.
+func CompileExpression(src string) (*expr.Program, error) {
+	return expressions.Compile(src)
+}
+
+// ExpressionRule fails transactions for which its expression is true. Match
+// holds the result of running Program against the transaction, or Err why it
+// could not be run.
+type ExpressionRule struct {
+	RuleBase
+	Program *expr.Program
+	Match   bool
+	Err     error
+}
+
+// newExpressionRule runs the rule's cached program against tx.
+func newExpressionRule(r *Rule, tx dto.Transaction, env Env) (ComplianceRule, error) {
+	if r.Expression == nil {
+		return nil, Skip("missing expression")
+	}
+	prog, err := CompileExpression(*r.Expression)
+	if err != nil {
+		return nil, Skip("invalid expression: " + err.Error())
+	}
+	match, err := prog.Eval(tx)
+	return &ExpressionRule{RuleBase: r.RuleBase, Program: prog, Match: match, Err: err}, nil
+}
+
+// Validate fails closed: an expression that cannot be evaluated, for example
+// because a metadata value has the wrong type or the cost limit was reached,
+// fails the rule with its Outcome, or manual_review, rather than letting the
+// transaction through.
+func (r *ExpressionRule) Validate(tx dto.Transaction) Decision {
+	if r.Err != nil {
+		return r.Fail(StatusManualReview, "Rule expression could not be evaluated: "+r.Err.Error())
+	}
+	if r.Match {
+		return r.Fail(StatusReject, "Transaction matches rule expression")
+	}
+	return Approve()
+}
+
+func (r *ExpressionRule) Inputs(tx dto.Transaction, dec Decision) map[string]any {
+	in := map[string]any{"expression": r.Program.String()}
+	if r.Err != nil {
+		in["error"] = r.Err.Error()
+	}
+	return in
+}
@@ -0,0 +1,46 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/warleon/ms4-compliance-service/internal/dto"
+	"github.com/warleon/ms4-compliance-service/internal/money"
+)
+
+func TestExpressionRuleFailsClosed(t *testing.T) {
+	src := `metadata.score > 10`
+	tx := func(score any) dto.Transaction {
+		return dto.Transaction{Amount: money.MustParse("100"), Metadata: map[string]any{"score": score}}
+	}
+	long := `contains(metadata.note, "x") && contains(metadata.note, "y") && contains(metadata.note, "z")`
+
+	for _, tc := range []struct {
+		name    string
+		src     string
+		outcome DecisionStatus
+		tx      dto.Transaction
+		want    DecisionStatus
+	}{
+		{"match", src, "", tx(20), StatusReject},
+		{"no match", src, "", tx(5), StatusApprove},
+		{"wrong type", src, "", tx("high"), StatusManualReview},
+		{"missing value", src, "", dto.Transaction{}, StatusManualReview},
+		{"configured outcome", src, StatusReject, tx("high"), StatusReject},
+		{"division by zero", `amount / metadata.score > 1`, "", tx(0), StatusManualReview},
+		{"cost exceeded", long, "", dto.Transaction{Metadata: map[string]any{"note": strings.Repeat("x", 200000)}}, StatusManualReview},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			src := tc.src
+			r := &Rule{RuleExtras: RuleExtras{Expression: &src}}
+			r.Type, r.Outcome = RuleTypeExpression, tc.outcome
+			dec, res, err := Evaluate(r, tc.tx, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Skipped || dec.Status != tc.want {
+				t.Fatalf("status = %s (skipped %v, %s), want %s", dec.Status, res.Skipped, dec.Reason, tc.want)
+			}
+		})
+	}
+}
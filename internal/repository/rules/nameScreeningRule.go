@@ -0,0 +1,148 @@
+package rules
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/warleon/ms4-compliance-service/internal/dto"
+	"github.com/warleon/ms4-compliance-service/internal/screening"
+)
+
+const (
+	// DefaultNameMetadataKey is the metadata entry read when a rule sets none.
+	DefaultNameMetadataKey = "partyName"
+	// DefaultMatchThreshold is the minimum score when a rule sets none.
+	DefaultMatchThreshold = 0.9
+	// maxNameMatches caps how many candidates a decision reports.
+	maxNameMatches = 5
+	// MaxPartyNameLength caps the characters of a screened name, since the
+	// cost of screening grows with its length times the size of the list.
+	MaxPartyNameLength = 256
+)
+
+// NameMatch is a sanctioned party whose name or alias resembles the screened name.
+type NameMatch struct {
+	SanctionID  uint           `json:"sanctionId"`
+	Name        string         `json:"name"`
+	MatchedName string         `json:"matchedName"`
+	Source      SanctionSource `json:"source,omitempty"`
+	Score       float64        `json:"score"`
+}
+
+// NameScreeningRule scores the party name found in the transaction metadata
+// against the names and aliases of Candidates. An exact match after
+// normalization is rejected; weaker matches above Threshold go to review,
+// unless the rule sets its own Outcome. A name longer than
+// MaxPartyNameLength is not scored and goes to review.
+type NameScreeningRule struct {
+	RuleBase
+	MetadataKey string
+	Threshold   float64
+	Candidates  []Sanction
+}
+
+// newNameScreeningRule screens against the sanctioned parties in env.
+func newNameScreeningRule(r *Rule, tx dto.Transaction, env Env) (ComplianceRule, error) {
+	nr := &NameScreeningRule{
+		RuleBase:    r.RuleBase,
+		MetadataKey: DefaultNameMetadataKey,
+		Threshold:   DefaultMatchThreshold,
+	}
+	if r.MetadataKey != nil && *r.MetadataKey != "" {
+		nr.MetadataKey = *r.MetadataKey
+	}
+	if r.MatchThreshold != nil {
+		nr.Threshold = *r.MatchThreshold
+	}
+	if nr.PartyName(tx) == "" {
+		return nil, Skip("transaction has no " + nr.MetadataKey)
+	}
+	var err error
+	if nr.Candidates, err = env.SanctionedParties(); err != nil {
+		return nil, err
+	}
+	return nr, nil
+}
+
+// NormalizeNames fills in Normalized for each party.
+func NormalizeNames(parties []Sanction) {
+	for i := range parties {
+		p := &parties[i]
+		p.Normalized = make([]string, 0, 1+len(p.Aliases))
+		for _, n := range append([]string{p.Name}, p.Aliases...) {
+			p.Normalized = append(p.Normalized, screening.Normalize(n))
+		}
+	}
+}
+
+// PartyName returns the name to screen, or "" when the transaction has none.
+func (r *NameScreeningRule) PartyName(tx dto.Transaction) string {
+	name, _ := tx.Metadata[r.MetadataKey].(string)
+	return strings.TrimSpace(name)
+}
+
+func (r *NameScreeningRule) Validate(tx dto.Transaction) Decision {
+	name := r.PartyName(tx)
+	if name == "" {
+		return Approve()
+	}
+	if utf8.RuneCountInString(name) > MaxPartyNameLength {
+		return r.Fail(StatusManualReview, fmt.Sprintf("Party name is longer than %d characters", MaxPartyNameLength))
+	}
+
+	normalized := screening.Normalize(name)
+	var matches []NameMatch
+	for _, c := range r.Candidates {
+		best := NameMatch{SanctionID: c.ID, Name: c.Name, Source: c.Source}
+		for i, candidate := range append([]string{c.Name}, c.Aliases...) {
+			var nc string
+			if len(c.Normalized) == 1+len(c.Aliases) {
+				nc = c.Normalized[i]
+			} else {
+				nc = screening.Normalize(candidate)
+			}
+			if score := screening.ScoreNormalized(normalized, nc); score > best.Score {
+				best.Score = score
+				best.MatchedName = candidate
+			}
+		}
+		if best.Score >= r.Threshold {
+			matches = append(matches, best)
+		}
+	}
+	if len(matches) == 0 {
+		return Approve()
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	if len(matches) > maxNameMatches {
+		matches = matches[:maxNameMatches]
+	}
+
+	var dec Decision
+	if matches[0].Score >= 1 {
+		dec = r.Fail(StatusReject, "Party name matches sanctioned party "+matches[0].Name)
+	} else {
+		dec = r.Fail(StatusManualReview, "Party name resembles sanctioned party "+matches[0].Name)
+	}
+	dec.Matches = matches
+	return dec
+}
+
+// Inputs records the best match by its scalar fields; the full list of
+// matches is kept in the decision.
+func (r *NameScreeningRule) Inputs(tx dto.Transaction, dec Decision) map[string]any {
+	in := map[string]any{
+		"name":       r.PartyName(tx),
+		"threshold":  r.Threshold,
+		"matchCount": len(dec.Matches),
+	}
+	if len(dec.Matches) > 0 {
+		best := dec.Matches[0]
+		in["bestSanctionId"] = best.SanctionID
+		in["bestMatchedName"] = best.MatchedName
+		in["bestScore"] = best.Score
+	}
+	return in
+}
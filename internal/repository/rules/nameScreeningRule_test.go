@@ -0,0 +1,77 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/warleon/ms4-compliance-service/internal/dto"
+)
+
+func TestNameScreening(t *testing.T) {
+	parties := []Sanction{
+		{Name: "Ivan Petrov", Aliases: []string{"Ivan Petroff"}},
+		{Name: "Acme Trading LLC"},
+	}
+	parties[0].ID, parties[1].ID = 1, 2
+	normalized := append([]Sanction(nil), parties...)
+	NormalizeNames(normalized)
+
+	for _, tc := range []struct {
+		name string
+		want DecisionStatus
+	}{
+		{"PETROV, Ivan", StatusReject},
+		{"Ivan Petrof", StatusManualReview},
+		{"Jane Doe", StatusApprove},
+		{strings.Repeat("Ivan Petrov ", 30), StatusManualReview},
+	} {
+		tx := dto.Transaction{Metadata: map[string]any{DefaultNameMetadataKey: tc.name}}
+		for _, candidates := range [][]Sanction{parties, normalized} {
+			r := &NameScreeningRule{MetadataKey: DefaultNameMetadataKey, Threshold: DefaultMatchThreshold, Candidates: candidates}
+			if dec := r.Validate(tx); dec.Status != tc.want {
+				t.Errorf("%.20q (normalized %v): status = %s, want %s", tc.name, candidates[0].Normalized != nil, dec.Status, tc.want)
+			}
+		}
+	}
+}
+
+func TestCheckMatchThreshold(t *testing.T) {
+	for _, th := range []float64{0, -0.5, 1.01} {
+		r := &Rule{RuleExtras: RuleExtras{MatchThreshold: &th}}
+		r.Type = RuleTypeNameScreening
+		if r.Check() == nil {
+			t.Errorf("matchThreshold %v accepted", th)
+		}
+	}
+	for _, th := range []float64{0.01, 0.9, 1} {
+		r := &Rule{RuleExtras: RuleExtras{MatchThreshold: &th}}
+		r.Type = RuleTypeNameScreening
+		if err := r.Check(); err != nil {
+			t.Errorf("matchThreshold %v: %v", th, err)
+		}
+	}
+}
+
+func TestNameScreeningInputs(t *testing.T) {
+	parties := []Sanction{{Name: "Ivan Petrov"}}
+	parties[0].ID = 1
+	r := &NameScreeningRule{MetadataKey: DefaultNameMetadataKey, Threshold: DefaultMatchThreshold, Candidates: parties}
+	for _, name := range []string{"Ivan Petrof", "Jane Doe"} {
+		tx := dto.Transaction{Metadata: map[string]any{DefaultNameMetadataKey: name}}
+		dec := r.Validate(tx)
+		in := r.Inputs(tx, dec)
+		for k, v := range in {
+			switch v.(type) {
+			case string, int, uint, float64:
+			default:
+				t.Errorf("%s: input %s is a %T, want a scalar", name, k, v)
+			}
+		}
+		if in["matchCount"] != len(dec.Matches) {
+			t.Errorf("%s: matchCount = %v, want %d", name, in["matchCount"], len(dec.Matches))
+		}
+		if len(dec.Matches) > 0 && (in["bestSanctionId"] != uint(1) || in["bestMatchedName"] != "Ivan Petrov" || in["bestScore"] != dec.Matches[0].Score) {
+			t.Errorf("%s: best match inputs = %v", name, in)
+		}
+	}
+}
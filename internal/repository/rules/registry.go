@@ -0,0 +1,97 @@
+package rules
+
+import (
+	"errors"
+	"time"
+
+	"github.com/warleon/ms4-compliance-service/internal/dto"
+	"github.com/warleon/ms4-compliance-service/internal/money"
+)
+
+// HistoryQuery selects recorded transactions for velocity and structuring
+// rules. Amounts only add up within one currency, so queries that sum them set
+// Currency; an empty Currency matches every currency.
+type HistoryQuery struct {
+	Key       VelocityKey
+	Value     string
+	Currency  string
+	Since     time.Time
+	MinAmount *money.Amount
+	MaxAmount *money.Amount
+}
+
+// Env gives rule factories access to the data rules check transactions
+// against.
+type Env interface {
+	// Now is the moment history windows are measured back from: the current
+	// time, or the time of the transaction when replaying the past.
+	Now() time.Time
+	// TransactionStats counts and sums the recorded transactions matching q
+	// that were recorded before Now.
+	TransactionStats(q HistoryQuery) (count int64, sum money.Amount, err error)
+	// SanctionedParties returns the sanctioned parties that have a name.
+	SanctionedParties() ([]Sanction, error)
+	// IsAccountSanctioned reports whether accID is on a sanctions list.
+	IsAccountSanctioned(accID string) (bool, error)
+	// Rule returns the rule with the given ID, or nil if there is none.
+	Rule(id uint) (*Rule, error)
+}
+
+// Factory builds the ComplianceRule for a stored rule, ready to validate tx.
+// It loads whatever the rule needs, such as history, through env. A Skip error
+// means the rule cannot be evaluated for tx; any other error aborts
+// validation.
+type Factory func(r *Rule, tx dto.Transaction, env Env) (ComplianceRule, error)
+
+// Skip is returned by a factory for a rule that cannot be evaluated, such as a
+// malformed rule or a transaction without the field the rule reads. The rule
+// approves and the trace records the reason.
+type Skip string
+
+func (s Skip) Error() string { return string(s) }
+
+var factories = make(map[RuleType]Factory)
+
+// Register makes rules of type t buildable with f, replacing any earlier
+// factory for t. It is meant to be called from init functions.
+func Register(t RuleType, f Factory) {
+	factories[t] = f
+}
+
+func init() {
+	Register(RuleTypeAmountThreshold, newAmountThresholdRule)
+	Register(RuleTypeSanctionsList, newBlacklistRule)
+	Register(RuleTypeVelocity, newVelocityRule)
+	Register(RuleTypeStructuring, newStructuringRule)
+	Register(RuleTypeNameScreening, newNameScreeningRule)
+	Register(RuleTypeExpression, newExpressionRule)
+	Register(RuleTypeComposite, newCompositeRule)
+}
+
+// Evaluate builds r with the factory registered for its type and validates
+// tx against it. It returns the decision and r's trace entry; rules that are
+// skipped approve.
+func Evaluate(r *Rule, tx dto.Transaction, env Env) (Decision, RuleResult, error) {
+	build, ok := factories[r.Type]
+	if !ok {
+		return Approve(), SkippedRuleResult(r.RuleBase, "unknown rule type "+string(r.Type)), nil
+	}
+	cr, err := build(r, tx, env)
+	var skip Skip
+	if errors.As(err, &skip) {
+		res := SkippedRuleResult(r.RuleBase, skip.Error())
+		if c, ok := cr.(*CompositeRule); ok {
+			res.Children = c.Results
+		}
+		return Approve(), res, nil
+	}
+	if err != nil {
+		return Decision{}, RuleResult{}, err
+	}
+	dec := cr.Validate(tx)
+	res := NewRuleResult(r.RuleBase, dec, cr.Inputs(tx, dec))
+	if c, ok := cr.(*CompositeRule); ok {
+		res.Children = c.Results
+	}
+	return dec, res, nil
+}
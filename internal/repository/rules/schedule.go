@@ -0,0 +1,152 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	// rule schedules name IANA time zones, which must resolve even on hosts
+	// without a zoneinfo database
+	_ "time/tzdata"
+)
+
+// RulePeriod selects rules by their effective dates relative to a moment.
+type RulePeriod string
+
+const (
+	PeriodUpcoming RulePeriod = "upcoming" // EffectiveFrom is still ahead
+	PeriodActive   RulePeriod = "active"   // within EffectiveFrom and EffectiveTo
+	PeriodExpired  RulePeriod = "expired"  // EffectiveTo has passed
+)
+
+// Valid reports whether p is a known period.
+func (p RulePeriod) Valid() bool {
+	return p == PeriodUpcoming || p == PeriodActive || p == PeriodExpired
+}
+
+// TimeWindow is a recurring window in which a rule is active, for example
+// weekday nights: {"days": ["mon", "tue", "wed", "thu", "fri"], "start":
+// "22:00", "end": "06:00", "timezone": "America/Lima"}. A window whose end is
+// before its start runs past midnight; days name the day it starts on.
+type TimeWindow struct {
+	Days     []string `json:"days,omitempty"` // mon..sun; empty is every day
+	Start    string   `json:"start"`          // HH:MM, inclusive
+	End      string   `json:"end"`            // HH:MM, exclusive
+	Timezone string   `json:"timezone,omitempty"`
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// clock parses HH:MM into minutes after midnight.
+func clock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, use HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// check reports a malformed window.
+func (w TimeWindow) check() error {
+	for _, d := range w.Days {
+		if _, ok := weekdays[strings.ToLower(d)]; !ok {
+			return fmt.Errorf("invalid day %q, use mon..sun", d)
+		}
+	}
+	start, err := clock(w.Start)
+	if err != nil {
+		return err
+	}
+	end, err := clock(w.End)
+	if err != nil {
+		return err
+	}
+	if start == end {
+		return errors.New("window start and end are equal")
+	}
+	if _, err := time.LoadLocation(w.Timezone); err != nil {
+		return fmt.Errorf("invalid timezone %q", w.Timezone)
+	}
+	return nil
+}
+
+// Contains reports whether t falls within the window. Malformed windows
+// contain nothing.
+func (w TimeWindow) Contains(t time.Time) bool {
+	loc, err := time.LoadLocation(w.Timezone)
+	if err != nil {
+		return false
+	}
+	start, err1 := clock(w.Start)
+	end, err2 := clock(w.End)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	t = t.In(loc)
+	now := t.Hour()*60 + t.Minute()
+	day := t.Weekday()
+	switch {
+	case start < end && now >= start && now < end:
+	case start > end && now >= start:
+	case start > end && now < end:
+		// the window started the day before
+		day = (day + 6) % 7
+	default:
+		return false
+	}
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, d := range w.Days {
+		if weekdays[strings.ToLower(d)] == day {
+			return true
+		}
+	}
+	return false
+}
+
+// Period reports whether the rule is upcoming, active or expired at t, judged
+// by its effective dates alone: it is active on or after EffectiveFrom and
+// before EffectiveTo.
+func (r RuleBase) Period(t time.Time) RulePeriod {
+	if r.EffectiveFrom != nil && t.Before(*r.EffectiveFrom) {
+		return PeriodUpcoming
+	}
+	if r.EffectiveTo != nil && !t.Before(*r.EffectiveTo) {
+		return PeriodExpired
+	}
+	return PeriodActive
+}
+
+// ActiveAt reports whether the rule is in effect at t: within its effective
+// dates, and inside one of its Schedule windows when it has any.
+func (r RuleBase) ActiveAt(t time.Time) bool {
+	if r.Period(t) != PeriodActive {
+		return false
+	}
+	if len(r.Schedule) == 0 {
+		return true
+	}
+	for _, w := range r.Schedule {
+		if w.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSchedule reports inverted effective dates or malformed windows.
+func (r *Rule) checkSchedule() error {
+	if r.EffectiveFrom != nil && r.EffectiveTo != nil && !r.EffectiveTo.After(*r.EffectiveFrom) {
+		return errors.New("effectiveTo must be after effectiveFrom")
+	}
+	for i, w := range r.Schedule {
+		if err := w.check(); err != nil {
+			return fmt.Errorf("schedule window %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
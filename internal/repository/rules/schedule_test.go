@@ -0,0 +1,115 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeWindowContains(t *testing.T) {
+	lima, err := time.LoadLocation("America/Lima") // UTC-5, no DST
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(day int, hhmm string, loc *time.Location) time.Time {
+		c, err := clock(hhmm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// January 6, 2025 is a Monday
+		return time.Date(2025, 1, 6+day, c/60, c%60, 0, 0, loc)
+	}
+	nights := TimeWindow{Days: []string{"MON"}, Start: "22:00", End: "06:00", Timezone: "America/Lima"}
+	office := TimeWindow{Start: "09:00", End: "17:00"}
+
+	for _, tc := range []struct {
+		name string
+		w    TimeWindow
+		t    time.Time
+		want bool
+	}{
+		{"start is inclusive", office, at(0, "09:00", time.UTC), true},
+		{"before start", office, at(0, "08:59", time.UTC), false},
+		{"end is exclusive", office, at(0, "17:00", time.UTC), false},
+		{"no days is every day", office, at(5, "12:00", time.UTC), true},
+		{"night starts on its day", nights, at(0, "22:00", lima), true},
+		{"night before start", nights, at(0, "21:59", lima), false},
+		{"night after midnight counts as the day it started", nights, at(1, "05:59", lima), true},
+		{"night end is exclusive", nights, at(1, "06:00", lima), false},
+		{"night on a day not listed", nights, at(1, "22:30", lima), false},
+		{"early morning of the listed day started the day before", nights, at(0, "05:00", lima), false},
+		{"time is read in the window's timezone", nights, at(1, "03:00", time.UTC), true},
+		{"night hours in UTC are not night hours in Lima", nights, at(0, "23:00", time.UTC), false},
+		{"malformed time", TimeWindow{Start: "9am", End: "17:00"}, at(0, "12:00", time.UTC), false},
+		{"unknown timezone", TimeWindow{Start: "09:00", End: "17:00", Timezone: "Mars/Olympus"}, at(0, "12:00", time.UTC), false},
+	} {
+		if got := tc.w.Contains(tc.t); got != tc.want {
+			t.Errorf("%s: Contains(%s) = %v, want %v", tc.name, tc.t, got, tc.want)
+		}
+	}
+}
+
+func TestCheckSchedule(t *testing.T) {
+	t0 := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	later := t0.Add(time.Hour)
+	ok := TimeWindow{Days: []string{"mon", "Fri"}, Start: "22:00", End: "06:00", Timezone: "Europe/Madrid"}
+	for _, tc := range []struct {
+		name     string
+		from, to *time.Time
+		schedule []TimeWindow
+		want     string // part of the error, empty if valid
+	}{
+		{"valid", &t0, &later, []TimeWindow{ok, {Start: "09:00", End: "17:00"}}, ""},
+		{"open ended", &t0, nil, nil, ""},
+		{"to before from", &later, &t0, nil, "effectiveTo must be after effectiveFrom"},
+		{"to equal to from", &t0, &t0, nil, "effectiveTo must be after effectiveFrom"},
+		{"unknown day", nil, nil, []TimeWindow{ok, {Days: []string{"monday"}, Start: "09:00", End: "17:00"}}, `schedule window 2: invalid day "monday"`},
+		{"malformed time", nil, nil, []TimeWindow{{Start: "9:00pm", End: "17:00"}}, `invalid time "9:00pm"`},
+		{"empty window", nil, nil, []TimeWindow{{Start: "09:00", End: "09:00"}}, "start and end are equal"},
+		{"unknown timezone", nil, nil, []TimeWindow{{Start: "09:00", End: "17:00", Timezone: "Lima"}}, `invalid timezone "Lima"`},
+	} {
+		r := &Rule{}
+		r.EffectiveFrom, r.EffectiveTo, r.Schedule = tc.from, tc.to, tc.schedule
+		err := r.checkSchedule()
+		switch {
+		case tc.want == "" && err != nil:
+			t.Errorf("%s: %v", tc.name, err)
+		case tc.want != "" && (err == nil || !strings.Contains(err.Error(), tc.want)):
+			t.Errorf("%s: err = %v, want one containing %q", tc.name, err, tc.want)
+		}
+	}
+}
+
+func TestPeriodAndActiveAt(t *testing.T) {
+	from := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC) // a Monday
+	to := from.Add(24 * time.Hour)
+	bounded := RuleBase{EffectiveFrom: &from, EffectiveTo: &to}
+	scheduled := bounded
+	scheduled.Schedule = []TimeWindow{{Start: "09:00", End: "12:00"}}
+
+	for _, tc := range []struct {
+		name   string
+		r      RuleBase
+		t      time.Time
+		period RulePeriod
+		active bool
+	}{
+		{"before effectiveFrom", bounded, from.Add(-time.Nanosecond), PeriodUpcoming, false},
+		{"effectiveFrom is inclusive", bounded, from, PeriodActive, true},
+		{"just before effectiveTo", bounded, to.Add(-time.Nanosecond), PeriodActive, true},
+		{"effectiveTo is exclusive", bounded, to, PeriodExpired, false},
+		{"no dates", RuleBase{}, from, PeriodActive, true},
+		{"only effectiveFrom", RuleBase{EffectiveFrom: &from}, to.Add(1000 * time.Hour), PeriodActive, true},
+		{"only effectiveTo", RuleBase{EffectiveTo: &to}, to, PeriodExpired, false},
+		{"in effect and in the window", scheduled, from.Add(2 * time.Hour), PeriodActive, true},
+		{"in effect but outside the window", scheduled, from.Add(4 * time.Hour), PeriodActive, false},
+		{"in the window but expired", scheduled, to.Add(time.Hour), PeriodExpired, false},
+	} {
+		if got := tc.r.Period(tc.t); got != tc.period {
+			t.Errorf("%s: Period = %s, want %s", tc.name, got, tc.period)
+		}
+		if got := tc.r.ActiveAt(tc.t); got != tc.active {
+			t.Errorf("%s: ActiveAt = %v, want %v", tc.name, got, tc.active)
+		}
+	}
+}
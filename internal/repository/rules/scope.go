@@ -0,0 +1,141 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/warleon/ms4-compliance-service/internal/dto"
+)
+
+// RuleScope says which transactions a rule applies to.
+type RuleScope string
+
+const (
+	ScopeGlobal   RuleScope = "global"
+	ScopeSegment  RuleScope = "segment"
+	ScopeCustomer RuleScope = "customer"
+	ScopeAccount  RuleScope = "account"
+)
+
+// SegmentMetadataKey is the transaction metadata entry holding the customer
+// segment matched by segment scoped rules.
+const SegmentMetadataKey = "segment"
+
+// specificity orders scopes: account beats customer, customer beats segment,
+// segment beats global.
+func (s RuleScope) specificity() int {
+	switch s {
+	case ScopeAccount:
+		return 3
+	case ScopeCustomer:
+		return 2
+	case ScopeSegment:
+		return 1
+	}
+	return 0
+}
+
+// Valid reports whether s is a known scope.
+func (s RuleScope) Valid() bool {
+	switch s {
+	case ScopeGlobal, ScopeSegment, ScopeCustomer, ScopeAccount:
+		return true
+	}
+	return false
+}
+
+// EffectiveScope returns the rule's scope and the value it applies to. Rules
+// without a Scope are account scoped when they set Account, otherwise global.
+func (r RuleBase) EffectiveScope() (RuleScope, string) {
+	switch {
+	case r.Scope != "":
+		return r.Scope, r.ScopeValue
+	case r.Account != "":
+		return ScopeAccount, r.Account
+	}
+	return ScopeGlobal, ""
+}
+
+// ScopeLabel describes the rule's scope for the trace, e.g. "customer:C-1".
+func (r RuleBase) ScopeLabel() string {
+	scope, value := r.EffectiveScope()
+	if scope == ScopeGlobal {
+		return string(scope)
+	}
+	return string(scope) + ":" + value
+}
+
+// AppliesTo reports whether tx falls within the rule's scope. Account scoped
+// rules apply to transactions from or to the account.
+func (r RuleBase) AppliesTo(tx dto.Transaction) bool {
+	scope, value := r.EffectiveScope()
+	switch scope {
+	case ScopeAccount:
+		return tx.FromAcc == value || tx.ToAcc == value
+	case ScopeCustomer:
+		return tx.CustomerID == value
+	case ScopeSegment:
+		segment, _ := tx.Metadata[SegmentMetadataKey].(string)
+		return segment == value
+	}
+	return true
+}
+
+// checkScope reports an unknown scope or a missing scope value.
+func (r *Rule) checkScope() error {
+	if r.Scope == "" {
+		return nil
+	}
+	if !r.Scope.Valid() {
+		return errors.New("scope must be global, segment, customer or account")
+	}
+	if r.Scope == ScopeGlobal && r.ScopeValue != "" {
+		return errors.New("global scope does not take a scopeValue")
+	}
+	if r.Scope != ScopeGlobal && r.ScopeValue == "" {
+		return fmt.Errorf("%s scope needs a scopeValue", r.Scope)
+	}
+	return nil
+}
+
+// Overridden is a rule that applies to a transaction but lost to a more
+// specific rule of the same type and group.
+type Overridden struct {
+	Rule *Rule
+	By   RuleScope
+}
+
+// Applicable picks the rules in all that apply to tx. Rules compete with the
+// rules of the same type and Group: of those whose scope matches tx, only the
+// ones with the most specific scope apply. Both lists keep the order of all.
+func Applicable(all []Rule, tx dto.Transaction) (apply []*Rule, overridden []Overridden) {
+	type group struct {
+		t RuleType
+		g string
+	}
+	best := make(map[group]RuleScope)
+	for i := range all {
+		r := &all[i]
+		if !r.AppliesTo(tx) {
+			continue
+		}
+		k := group{r.Type, r.Group}
+		scope, _ := r.EffectiveScope()
+		if b, ok := best[k]; !ok || scope.specificity() > b.specificity() {
+			best[k] = scope
+		}
+	}
+	for i := range all {
+		r := &all[i]
+		if !r.AppliesTo(tx) {
+			continue
+		}
+		scope, _ := r.EffectiveScope()
+		if b := best[group{r.Type, r.Group}]; scope.specificity() < b.specificity() {
+			overridden = append(overridden, Overridden{Rule: r, By: b})
+			continue
+		}
+		apply = append(apply, r)
+	}
+	return apply, overridden
+}
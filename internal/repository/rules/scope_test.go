@@ -0,0 +1,71 @@
+package rules
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/warleon/ms4-compliance-service/internal/dto"
+)
+
+func scoped(id uint, t RuleType, group string, scope RuleScope, value string) Rule {
+	r := Rule{}
+	r.ID, r.Type, r.Group, r.Scope, r.ScopeValue = id, t, group, scope, value
+	return r
+}
+
+func TestApplicable(t *testing.T) {
+	all := []Rule{
+		scoped(1, RuleTypeAmountThreshold, "", "", ""),
+		scoped(2, RuleTypeAmountThreshold, "", ScopeSegment, "retail"),
+		scoped(3, RuleTypeAmountThreshold, "", ScopeCustomer, "C1"),
+		scoped(4, RuleTypeAmountThreshold, "", ScopeAccount, "A1"),
+		// a second group of the same type competes only within itself
+		scoped(5, RuleTypeAmountThreshold, "large", ScopeGlobal, ""),
+		scoped(6, RuleTypeAmountThreshold, "large", ScopeCustomer, "C2"),
+		// another type is never overridden by amount rules
+		scoped(7, RuleTypeVelocity, "", "", ""),
+		// Account without a Scope is account scoped
+		func() Rule { r := scoped(8, RuleTypeVelocity, "", "", ""); r.Account = "A2"; return r }(),
+	}
+
+	type over struct {
+		ID uint
+		By RuleScope
+	}
+	for _, tc := range []struct {
+		name       string
+		tx         dto.Transaction
+		apply      []uint
+		overridden []over
+	}{
+		{"global only", dto.Transaction{FromAcc: "X", CustomerID: "C9"},
+			[]uint{1, 5, 7}, nil},
+		{"segment beats global", dto.Transaction{FromAcc: "X", CustomerID: "C9", Metadata: map[string]any{"segment": "retail"}},
+			[]uint{2, 5, 7}, []over{{1, ScopeSegment}}},
+		{"customer beats segment", dto.Transaction{FromAcc: "X", CustomerID: "C1", Metadata: map[string]any{"segment": "retail"}},
+			[]uint{3, 5, 7}, []over{{1, ScopeCustomer}, {2, ScopeCustomer}}},
+		{"account beats customer", dto.Transaction{FromAcc: "X", ToAcc: "A1", CustomerID: "C1"},
+			[]uint{4, 5, 7}, []over{{1, ScopeAccount}, {3, ScopeAccount}}},
+		{"groups do not interact", dto.Transaction{FromAcc: "A1", CustomerID: "C2"},
+			[]uint{4, 6, 7}, []over{{1, ScopeAccount}, {5, ScopeCustomer}}},
+		{"legacy account rule", dto.Transaction{FromAcc: "A2"},
+			[]uint{1, 5, 8}, []over{{7, ScopeAccount}}},
+		{"segment of another type", dto.Transaction{Metadata: map[string]any{"segment": 5}},
+			[]uint{1, 5, 7}, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			apply, overridden := Applicable(all, tc.tx)
+			var gotApply []uint
+			for _, r := range apply {
+				gotApply = append(gotApply, r.ID)
+			}
+			var gotOver []over
+			for _, o := range overridden {
+				gotOver = append(gotOver, over{o.Rule.ID, o.By})
+			}
+			if !reflect.DeepEqual(gotApply, tc.apply) || !reflect.DeepEqual(gotOver, tc.overridden) {
+				t.Fatalf("apply %v, overridden %v; want %v, %v", gotApply, gotOver, tc.apply, tc.overridden)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package rules
+
+import "errors"
+
+// MaxRiskScore is the top of the risk score scale.
+const MaxRiskScore = 100
+
+// Default weights for rules without one, by the status they fail with.
+const (
+	DefaultRejectWeight = 100
+	DefaultReviewWeight = 50
+)
+
+// Contribution returns the points a rule adds to the risk score for dec. A
+// passing rule adds nothing; a failing one adds its Weight, or the default
+// weight for the failing status. Name screening matches scale the weight by
+// the best match score.
+func (r RuleBase) Contribution(dec Decision) float64 {
+	if dec.Status == StatusApprove {
+		return 0
+	}
+	w := float64(DefaultRejectWeight)
+	if dec.Status == StatusManualReview {
+		w = DefaultReviewWeight
+	}
+	if r.Weight != nil {
+		w = *r.Weight
+	}
+	if len(dec.Matches) > 0 {
+		w *= dec.Matches[0].Score
+	}
+	return w
+}
+
+// RiskScore sums the contributions in trace, capped at MaxRiskScore. Shadow
+// results do not count.
+func RiskScore(trace []RuleResult) float64 {
+	var score float64
+	for _, r := range trace {
+		if !r.Shadow {
+			score += r.Score
+		}
+	}
+	return min(score, MaxRiskScore)
+}
+
+// RiskBands maps a risk score to a status: scores at or above Reject are
+// rejected, at or above Review go to manual review, and lower ones approve.
+// A zero Reject leaves out the reject band, so the score alone never rejects
+// and failures that only ask for review stay in review however many add up.
+type RiskBands struct {
+	Review float64 `json:"review"`
+	Reject float64 `json:"reject,omitempty"`
+}
+
+// DefaultRiskBands are used when no bands are configured. They have no reject
+// band; setting one is an explicit choice.
+var DefaultRiskBands = RiskBands{Review: 40}
+
+// Check reports whether the bands are usable.
+func (b RiskBands) Check() error {
+	if b.Review <= 0 || b.Review > MaxRiskScore {
+		return errors.New("risk review band must satisfy 0 < review <= 100")
+	}
+	if b.Reject != 0 && (b.Review > b.Reject || b.Reject > MaxRiskScore) {
+		return errors.New("risk bands must satisfy 0 < review <= reject <= 100")
+	}
+	return nil
+}
+
+// Status returns the status for score.
+func (b RiskBands) Status(score float64) DecisionStatus {
+	switch {
+	case b.Reject > 0 && score >= b.Reject:
+		return StatusReject
+	case score >= b.Review:
+		return StatusManualReview
+	}
+	return StatusApprove
+}
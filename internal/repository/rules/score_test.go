@@ -0,0 +1,96 @@
+package rules
+
+import "testing"
+
+func weight(w float64) *float64 { return &w }
+
+func TestContribution(t *testing.T) {
+	match := func(score float64) Decision {
+		d := NewDecision(StatusManualReview, "match")
+		d.Matches = []NameMatch{{Score: score}, {Score: 0.1}}
+		return d
+	}
+	for _, tc := range []struct {
+		name   string
+		weight *float64
+		dec    Decision
+		want   float64
+	}{
+		{"approve", weight(30), Approve(), 0},
+		{"default reject", nil, NewDecision(StatusReject, "x"), DefaultRejectWeight},
+		{"default review", nil, NewDecision(StatusManualReview, "x"), DefaultReviewWeight},
+		{"weighted", weight(30), NewDecision(StatusReject, "x"), 30},
+		{"zero weight", weight(0), NewDecision(StatusReject, "x"), 0},
+		{"best match scales the weight", weight(50), match(0.9), 45},
+		{"best match scales the default", nil, match(0.5), DefaultReviewWeight * 0.5},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := RuleBase{Weight: tc.weight}
+			if got := r.Contribution(tc.dec); got != tc.want {
+				t.Fatalf("Contribution = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRiskScore(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		trace []RuleResult
+		want  float64
+	}{
+		{"empty", nil, 0},
+		{"sum", []RuleResult{{Score: 20}, {Score: 0}, {Score: 15.5}}, 35.5},
+		{"capped", []RuleResult{{Score: 70}, {Score: 70}}, MaxRiskScore},
+		{"shadow left out", []RuleResult{{Score: 20}, {Score: 100, Shadow: true}}, 20},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := RiskScore(tc.trace); got != tc.want {
+				t.Fatalf("RiskScore = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRiskBandsCheck(t *testing.T) {
+	for _, tc := range []struct {
+		bands RiskBands
+		ok    bool
+	}{
+		{DefaultRiskBands, true},
+		{RiskBands{Review: 40, Reject: 80}, true},
+		{RiskBands{Review: 50, Reject: 50}, true},
+		{RiskBands{Review: 100}, true},
+		{RiskBands{Review: 0, Reject: 80}, false},
+		{RiskBands{Review: -1}, false},
+		{RiskBands{Review: 101}, false},
+		{RiskBands{Review: 80, Reject: 40}, false},
+		{RiskBands{Review: 40, Reject: 101}, false},
+	} {
+		if err := tc.bands.Check(); (err == nil) != tc.ok {
+			t.Errorf("%+v: Check() = %v, want ok %v", tc.bands, err, tc.ok)
+		}
+	}
+}
+
+func TestRiskBandsStatus(t *testing.T) {
+	for _, tc := range []struct {
+		bands RiskBands
+		score float64
+		want  DecisionStatus
+	}{
+		{RiskBands{Review: 40, Reject: 80}, 0, StatusApprove},
+		{RiskBands{Review: 40, Reject: 80}, 39.99, StatusApprove},
+		{RiskBands{Review: 40, Reject: 80}, 40, StatusManualReview},
+		{RiskBands{Review: 40, Reject: 80}, 79.99, StatusManualReview},
+		{RiskBands{Review: 40, Reject: 80}, 80, StatusReject},
+		{RiskBands{Review: 40, Reject: 80}, 100, StatusReject},
+		// without a reject band the score never rejects
+		{DefaultRiskBands, 39, StatusApprove},
+		{DefaultRiskBands, 100, StatusManualReview},
+	} {
+		if got := tc.bands.Status(tc.score); got != tc.want {
+			t.Errorf("%+v: Status(%v) = %s, want %s", tc.bands, tc.score, got, tc.want)
+		}
+	}
+}
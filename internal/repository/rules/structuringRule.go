@@ -0,0 +1,94 @@
+package rules
+
+import (
+	"time"
+
+	"github.com/warleon/ms4-compliance-service/internal/dto"
+	"github.com/warleon/ms4-compliance-service/internal/money"
+)
+
+// MaxTolerance is the widest band, in percent below the threshold, that a
+// structuring rule may treat as "just under" it.
+const MaxTolerance = 50
+
+// StructuringRule flags customers splitting a payment into several transfers
+// that each sit just under Threshold. Count and Sum hold the customer's recent
+// transactions in the same currency inside the tolerance band, excluding the
+// one being validated.
+type StructuringRule struct {
+	RuleBase
+	Threshold money.Amount
+	Tolerance float64
+	MinCount  int64
+	Window    time.Duration
+	Count     int64
+	Sum       money.Amount
+}
+
+// newStructuringRule loads the customer's recent transactions in the band,
+// when the transaction itself falls in it.
+func newStructuringRule(r *Rule, tx dto.Transaction, env Env) (ComplianceRule, error) {
+	if r.Threshold == nil || r.Tolerance == nil || r.Window == nil {
+		return nil, Skip("incomplete structuring settings")
+	}
+	window, err := time.ParseDuration(*r.Window)
+	if err != nil || window <= 0 {
+		return nil, Skip("invalid window")
+	}
+	sr := &StructuringRule{
+		RuleBase:  r.RuleBase,
+		Threshold: *r.Threshold,
+		Tolerance: *r.Tolerance,
+		MinCount:  2,
+		Window:    window,
+	}
+	if r.MinCount != nil {
+		sr.MinCount = *r.MinCount
+	}
+	if tx.CustomerID == "" || !sr.InBand(tx.Amount) {
+		return sr, nil
+	}
+	floor := sr.Floor()
+	sr.Count, sr.Sum, err = env.TransactionStats(HistoryQuery{
+		Key:       VelocityKeyCustomerID,
+		Value:     tx.CustomerID,
+		Currency:  tx.Currency,
+		Since:     env.Now().Add(-window),
+		MinAmount: &floor,
+		MaxAmount: &sr.Threshold,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sr, nil
+}
+
+// Floor is the lowest amount considered "just under" the threshold.
+func (r *StructuringRule) Floor() money.Amount {
+	return r.Threshold.Mul(1 - r.Tolerance/100)
+}
+
+// InBand reports whether amount falls within the tolerance band below the threshold.
+func (r *StructuringRule) InBand(amount money.Amount) bool {
+	return amount.Cmp(r.Floor()) >= 0 && amount.Cmp(r.Threshold) < 0
+}
+
+func (r *StructuringRule) Validate(tx dto.Transaction) Decision {
+	if r.InBand(tx.Amount) && r.Count+1 >= r.MinCount && r.Sum.Add(tx.Amount).Cmp(r.Threshold) > 0 {
+		return r.Fail(StatusManualReview, "Possible structuring: repeated transactions just under threshold")
+	}
+	return Approve()
+}
+
+func (r *StructuringRule) Inputs(tx dto.Transaction, dec Decision) map[string]any {
+	return map[string]any{
+		"amount":    tx.Amount,
+		"currency":  tx.Currency,
+		"threshold": r.Threshold,
+		"floor":     r.Floor(),
+		"window":    r.Window.String(),
+		"count":     r.Count,
+		"sum":       r.Sum,
+		"minCount":  r.MinCount,
+	}
+}
@@ -0,0 +1,54 @@
+package rules
+
+import (
+	"testing"
+	"time"
+
+	"github.com/warleon/ms4-compliance-service/internal/dto"
+	"github.com/warleon/ms4-compliance-service/internal/money"
+)
+
+func TestStructuringPerCurrency(t *testing.T) {
+	now := time.Now()
+	past := func(amount, currency string) dto.Transaction {
+		return dto.Transaction{CustomerID: "C", Amount: money.MustParse(amount), Currency: currency, Timestamp: now.Add(-time.Minute)}
+	}
+	env := historyEnv{now: now, past: []dto.Transaction{past("9500", "JPY"), past("9800", "USD")}}
+
+	threshold, tolerance, window := money.MustParse("10000"), 10.0, "24h"
+	r := &Rule{RuleExtras: RuleExtras{Threshold: &threshold, Tolerance: &tolerance, Window: &window}}
+	r.Type = RuleTypeStructuring
+
+	for _, tc := range []struct {
+		amount, currency string
+		want             DecisionStatus
+	}{
+		{"9600", "USD", StatusManualReview},
+		{"9600", "EUR", StatusApprove},
+		{"5000", "USD", StatusApprove}, // below the band
+	} {
+		tx := dto.Transaction{CustomerID: "C", Amount: money.MustParse(tc.amount), Currency: tc.currency}
+		dec, _, err := Evaluate(r, tx, env)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if dec.Status != tc.want {
+			t.Errorf("%s %s: status = %s, want %s", tc.amount, tc.currency, dec.Status, tc.want)
+		}
+	}
+}
+
+func TestCheckTolerance(t *testing.T) {
+	for _, tc := range []struct {
+		tolerance float64
+		ok        bool
+	}{
+		{-5, false}, {0, false}, {0.5, true}, {10, true}, {MaxTolerance, true}, {MaxTolerance + 1, false}, {1e9, false},
+	} {
+		r := &Rule{RuleExtras: RuleExtras{Tolerance: &tc.tolerance}}
+		r.Type = RuleTypeStructuring
+		if err := r.Check(); (err == nil) != tc.ok {
+			t.Errorf("tolerance %v: err = %v, want ok %v", tc.tolerance, err, tc.ok)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package rules
+
+// RuleResult records how a single rule evaluated a transaction. A slice of
+// results forms the decision trace stored with each audit entry.
+type RuleResult struct {
+	RuleID  uint           `json:"ruleId"`
+	Version uint           `json:"version,omitempty"` // rule version evaluated
+	Name    string         `json:"name"`
+	Type    RuleType       `json:"type"`
+	Scope   string         `json:"scope,omitempty"` // scope the rule matched, e.g. "customer:C-1"
+	Inputs  map[string]any `json:"inputs,omitempty"`
+	Skipped bool           `json:"skipped,omitempty"`
+	Shadow  bool           `json:"shadow,omitempty"` // result of a shadow rule, not part of the decision
+	Status  DecisionStatus `json:"status"`
+	Reason  string         `json:"reason"`
+	// Score is the rule's contribution to the risk score.
+	Score float64 `json:"score,omitempty"`
+	// Children holds how each child of a composite rule evaluated.
+	Children []RuleResult `json:"children,omitempty"`
+}
+
+// NewRuleResult builds a trace entry for a rule from its decision.
+func NewRuleResult(r RuleBase, dec Decision, inputs map[string]any) RuleResult {
+	return RuleResult{
+		RuleID:  r.ID,
+		Version: r.Version,
+		Name:    r.Name,
+		Type:    r.Type,
+		Scope:   r.ScopeLabel(),
+		Inputs:  inputs,
+		Shadow:  r.Shadow(),
+		Status:  dec.Status,
+		Reason:  dec.Reason,
+		Score:   r.Contribution(dec),
+	}
+}
+
+// SkippedRuleResult builds a trace entry for a rule that could not be evaluated.
+func SkippedRuleResult(r RuleBase, reason string) RuleResult {
+	return RuleResult{
+		RuleID:  r.ID,
+		Version: r.Version,
+		Name:    r.Name,
+		Type:    r.Type,
+		Scope:   r.ScopeLabel(),
+		Shadow:  r.Shadow(),
+		Skipped: true,
+		Status:  StatusApprove,
+		Reason:  reason,
+	}
+}
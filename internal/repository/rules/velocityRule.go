@@ -0,0 +1,114 @@
+package rules
+
+import (
+	"time"
+
+	"github.com/warleon/ms4-compliance-service/internal/dto"
+	"github.com/warleon/ms4-compliance-service/internal/money"
+)
+
+// VelocityKey selects the transaction field used to group history for velocity checks.
+type VelocityKey string
+
+const (
+	VelocityKeyFromAcc    VelocityKey = "from_acc"
+	VelocityKeyToAcc      VelocityKey = "to_acc"
+	VelocityKeyCustomerID VelocityKey = "customer_id"
+)
+
+// Valid reports whether the key is one of the supported fields.
+func (k VelocityKey) Valid() bool {
+	switch k {
+	case VelocityKeyFromAcc, VelocityKeyToAcc, VelocityKeyCustomerID:
+		return true
+	}
+	return false
+}
+
+// Value extracts the grouping value from a transaction.
+func (k VelocityKey) Value(tx dto.Transaction) string {
+	switch k {
+	case VelocityKeyFromAcc:
+		return tx.FromAcc
+	case VelocityKeyToAcc:
+		return tx.ToAcc
+	case VelocityKeyCustomerID:
+		return tx.CustomerID
+	}
+	return ""
+}
+
+// VelocityRule limits how many transactions, and how much volume, a key may
+// produce within Window. Count and Sum hold the history already recorded for
+// the key inside the window, excluding the transaction being validated. Count
+// covers every currency; Sum only the transaction's own currency, as amounts
+// in different currencies cannot be added up.
+type VelocityRule struct {
+	RuleBase
+	Window    time.Duration
+	MaxCount  *int64
+	MaxAmount *money.Amount
+	Key       VelocityKey
+	Count     int64
+	Sum       money.Amount
+}
+
+// newVelocityRule loads the history recorded for the rule's key.
+func newVelocityRule(r *Rule, tx dto.Transaction, env Env) (ComplianceRule, error) {
+	if r.Window == nil || r.KeyField == nil || !r.KeyField.Valid() ||
+		(r.MaxCount == nil && r.MaxAmount == nil) {
+		return nil, Skip("incomplete velocity settings")
+	}
+	window, err := time.ParseDuration(*r.Window)
+	if err != nil || window <= 0 {
+		return nil, Skip("invalid window")
+	}
+	key := r.KeyField.Value(tx)
+	if key == "" {
+		return nil, Skip("transaction has no " + string(*r.KeyField))
+	}
+	vr := &VelocityRule{
+		RuleBase:  r.RuleBase,
+		Window:    window,
+		MaxCount:  r.MaxCount,
+		MaxAmount: r.MaxAmount,
+		Key:       *r.KeyField,
+	}
+	q := HistoryQuery{Key: vr.Key, Value: key, Since: env.Now().Add(-window)}
+	if vr.MaxCount != nil {
+		if vr.Count, _, err = env.TransactionStats(q); err != nil {
+			return nil, err
+		}
+	}
+	if vr.MaxAmount != nil {
+		q.Currency = tx.Currency
+		if _, vr.Sum, err = env.TransactionStats(q); err != nil {
+			return nil, err
+		}
+	}
+	return vr, nil
+}
+
+func (r *VelocityRule) Validate(tx dto.Transaction) Decision {
+	if r.MaxCount != nil && r.Count+1 > *r.MaxCount {
+		return r.Fail(StatusReject, "Transaction count exceeds velocity limit")
+	}
+	if r.MaxAmount != nil && r.Sum.Add(tx.Amount).Cmp(*r.MaxAmount) > 0 {
+		return r.Fail(StatusReject, "Transaction volume exceeds velocity limit")
+	}
+	return Approve()
+}
+
+func (r *VelocityRule) Inputs(tx dto.Transaction, dec Decision) map[string]any {
+	return map[string]any{
+		"keyField":  r.Key,
+		"key":       r.Key.Value(tx),
+		"window":    r.Window.String(),
+		"count":     r.Count,
+		"sum":       r.Sum,
+		"amount":    tx.Amount,
+		"currency":  tx.Currency,
+		"maxCount":  r.MaxCount,
+		"maxAmount": r.MaxAmount,
+	}
+}
@@ -0,0 +1,75 @@
+package rules
+
+import (
+	"testing"
+	"time"
+
+	"github.com/warleon/ms4-compliance-service/internal/dto"
+	"github.com/warleon/ms4-compliance-service/internal/money"
+)
+
+// historyEnv answers history queries from a list of past transactions.
+type historyEnv struct {
+	Env
+	now  time.Time
+	past []dto.Transaction
+}
+
+func (e historyEnv) Now() time.Time { return e.now }
+
+func (e historyEnv) TransactionStats(q HistoryQuery) (int64, money.Amount, error) {
+	var n int64
+	var sum money.Amount
+	for _, tx := range e.past {
+		if q.Key.Value(tx) != q.Value || (q.Currency != "" && tx.Currency != q.Currency) ||
+			tx.Timestamp.Before(q.Since) ||
+			(q.MinAmount != nil && tx.Amount.Cmp(*q.MinAmount) < 0) ||
+			(q.MaxAmount != nil && tx.Amount.Cmp(*q.MaxAmount) >= 0) {
+			continue
+		}
+		n++
+		sum = sum.Add(tx.Amount)
+	}
+	return n, sum, nil
+}
+
+func TestVelocityVolumePerCurrency(t *testing.T) {
+	now := time.Now()
+	past := func(amount, currency string) dto.Transaction {
+		return dto.Transaction{FromAcc: "A", Amount: money.MustParse(amount), Currency: currency, Timestamp: now.Add(-time.Minute)}
+	}
+	env := historyEnv{now: now, past: []dto.Transaction{past("600", "USD"), past("90000", "JPY")}}
+
+	window, key := "1h", VelocityKeyFromAcc
+	maxAmount, maxCount := money.MustParse("1000"), int64(3)
+	r := &Rule{RuleExtras: RuleExtras{Window: &window, KeyField: &key, MaxAmount: &maxAmount, MaxCount: &maxCount}}
+	r.Type = RuleTypeVelocity
+
+	for _, tc := range []struct {
+		amount, currency string
+		want             DecisionStatus
+	}{
+		{"300", "USD", StatusApprove}, // 900 USD
+		{"500", "USD", StatusReject},  // 1100 USD
+		{"500", "EUR", StatusApprove}, // JPY and USD volume does not count
+	} {
+		tx := dto.Transaction{FromAcc: "A", Amount: money.MustParse(tc.amount), Currency: tc.currency}
+		dec, _, err := Evaluate(r, tx, env)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if dec.Status != tc.want {
+			t.Errorf("%s %s: status = %s, want %s", tc.amount, tc.currency, dec.Status, tc.want)
+		}
+	}
+
+	// the count covers every currency
+	maxCount = 2
+	dec, _, err := Evaluate(r, dto.Transaction{FromAcc: "A", Amount: money.MustParse("1"), Currency: "EUR"}, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dec.Status != StatusReject {
+		t.Errorf("third transaction in the window: status = %s, want reject", dec.Status)
+	}
+}
@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"expvar"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/warleon/ms4-compliance-service/internal/repository/rules"
+)
+
+// Sanctions index metrics, served with the other expvars on /debug/vars.
+var (
+	indexAccounts      = expvar.NewInt("sanctions_index_accounts")
+	indexParties       = expvar.NewInt("sanctions_index_parties")
+	indexLastRefresh   = expvar.NewString("sanctions_index_last_refresh")
+	indexRefreshErrors = expvar.NewInt("sanctions_index_refresh_errors")
+)
+
+// SanctionsIndex keeps the sanctioned account identifiers and named parties in
+// memory so screening does not hit the database for every transaction.
+type SanctionsIndex struct {
+	source Repository
+
+	// refreshMu runs one refresh at a time, from read to swap, so an older
+	// read never replaces a newer one.
+	refreshMu sync.Mutex
+
+	mu       sync.RWMutex
+	loaded   bool
+	accounts map[string]struct{}
+	parties  []rules.Sanction
+}
+
+// Refresh reloads the index from the database. Concurrent refreshes, such as
+// a timed one and one after a change, run one after the other.
+func (i *SanctionsIndex) Refresh() error {
+	i.refreshMu.Lock()
+	defer i.refreshMu.Unlock()
+
+	accounts, err := i.source.ListSanctionedAccounts()
+	if err != nil {
+		indexRefreshErrors.Add(1)
+		return err
+	}
+	parties, err := i.source.FindSanctionedParties()
+	if err != nil {
+		indexRefreshErrors.Add(1)
+		return err
+	}
+	set := make(map[string]struct{}, len(accounts))
+	for _, a := range accounts {
+		set[a] = struct{}{}
+	}
+
+	i.mu.Lock()
+	i.accounts = set
+	i.parties = parties
+	i.loaded = true
+	i.mu.Unlock()
+
+	indexAccounts.Set(int64(len(set)))
+	indexParties.Set(int64(len(parties)))
+	indexLastRefresh.Set(time.Now().UTC().Format(time.RFC3339))
+	return nil
+}
+
+// Run refreshes the index every interval. It never returns.
+func (i *SanctionsIndex) Run(interval time.Duration) {
+	for range time.Tick(interval) {
+		if err := i.Refresh(); err != nil {
+			logrus.WithError(err).Warn("sanctions index refresh failed")
+		}
+	}
+}
+
+// refreshAfterChange is called once a change to the sanctions table is committed.
+func (i *SanctionsIndex) refreshAfterChange() {
+	if err := i.Refresh(); err != nil {
+		logrus.WithError(err).Warn("sanctions index refresh after change failed")
+	}
+}
+
+func (i *SanctionsIndex) contains(accID string) (found bool, ok bool) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	if !i.loaded {
+		return false, false
+	}
+	_, found = i.accounts[accID]
+	return found, true
+}
+
+func (i *SanctionsIndex) namedParties() ([]rules.Sanction, bool) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.parties, i.loaded
+}
+
+// indexedRepo answers sanctions lookups from a SanctionsIndex and refreshes it
+// whenever the sanctions table changes. Everything else goes to the wrapped
+// repository. Inside a transaction that changed sanctions, lookups fall back
+// to the database so the transaction sees its own writes.
+type indexedRepo struct {
+	Repository
+	index *SanctionsIndex
+	inTx  bool
+	dirty bool
+}
+
+// NewIndexedRepository wraps repo so that IsAccountSanctioned and
+// FindSanctionedParties are served from memory. The index is loaded before
+// returning; if that fails the error is returned along with a repository that
+// falls back to the database until a later refresh succeeds.
+func NewIndexedRepository(repo Repository) (Repository, *SanctionsIndex, error) {
+	index := &SanctionsIndex{source: repo}
+	err := index.Refresh()
+	return &indexedRepo{Repository: repo, index: index}, index, err
+}
+
+func (r *indexedRepo) WithTx(fn func(repo Repository) error) error {
+	txRepo := &indexedRepo{index: r.index, inTx: true}
+	err := r.Repository.WithTx(func(tx Repository) error {
+		txRepo.Repository = tx
+		return fn(txRepo)
+	})
+	if err == nil && txRepo.dirty {
+		r.changed()
+	}
+	return err
+}
+
+func (r *indexedRepo) changed() {
+	if r.inTx {
+		r.dirty = true
+		return
+	}
+	r.index.refreshAfterChange()
+}
+
+func (r *indexedRepo) IsAccountSanctioned(accID string) (bool, error) {
+	if accID == "" {
+		return false, nil
+	}
+	if !r.dirty {
+		if found, ok := r.index.contains(accID); ok {
+			return found, nil
+		}
+	}
+	return r.Repository.IsAccountSanctioned(accID)
+}
+
+func (r *indexedRepo) FindSanctionedParties() ([]rules.Sanction, error) {
+	if !r.dirty {
+		if parties, ok := r.index.namedParties(); ok {
+			return parties, nil
+		}
+	}
+	return r.Repository.FindSanctionedParties()
+}
+
+func (r *indexedRepo) CreateSanction(s *rules.Sanction) error {
+	if err := r.Repository.CreateSanction(s); err != nil {
+		return err
+	}
+	r.changed()
+	return nil
+}
+
+func (r *indexedRepo) DeleteSanction(id uint) error {
+	if err := r.Repository.DeleteSanction(id); err != nil {
+		return err
+	}
+	r.changed()
+	return nil
+}
+
+func (r *indexedRepo) CreateSanctions(s []rules.Sanction) error {
+	if err := r.Repository.CreateSanctions(s); err != nil {
+		return err
+	}
+	r.changed()
+	return nil
+}
+
+func (r *indexedRepo) DeleteSanctionsBySource(source rules.SanctionSource) (int64, error) {
+	n, err := r.Repository.DeleteSanctionsBySource(source)
+	if err != nil {
+		return 0, err
+	}
+	r.changed()
+	return n, nil
+}
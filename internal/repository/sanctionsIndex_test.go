@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/warleon/ms4-compliance-service/internal/repository/rules"
+)
+
+// slowSource serves sanctioned accounts, letting the test hold a refresh
+// between reading the accounts and installing them.
+type slowSource struct {
+	Repository
+	mu       sync.Mutex
+	accounts []string
+	reading  chan struct{} // receives when a read starts, if set
+	release  chan struct{} // a read waits for it, if set
+}
+
+func (s *slowSource) ListSanctionedAccounts() ([]string, error) {
+	s.mu.Lock()
+	out := append([]string(nil), s.accounts...)
+	reading, release := s.reading, s.release
+	s.reading, s.release = nil, nil
+	s.mu.Unlock()
+	if reading != nil {
+		reading <- struct{}{}
+		<-release
+	}
+	return out, nil
+}
+
+func (s *slowSource) FindSanctionedParties() ([]rules.Sanction, error) { return nil, nil }
+
+func TestRefreshKeepsNewestSnapshot(t *testing.T) {
+	reading, release := make(chan struct{}), make(chan struct{})
+	src := &slowSource{accounts: []string{"OLD"}, reading: reading, release: release}
+	index := &SanctionsIndex{source: src}
+
+	// a refresh reads the old list and stalls before installing it
+	first := make(chan error)
+	go func() { first <- index.Refresh() }()
+	<-reading
+
+	// the list changes and a second refresh runs, or waits for the first
+	src.mu.Lock()
+	src.accounts = []string{"NEW"}
+	src.mu.Unlock()
+	second := make(chan error, 1)
+	go func() { second <- index.Refresh() }()
+	select {
+	case err := <-second:
+		second <- err
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-first; err != nil {
+		t.Fatal(err)
+	}
+	if err := <-second; err != nil {
+		t.Fatal(err)
+	}
+	if found, _ := index.contains("NEW"); !found {
+		t.Fatal("index lost the newer snapshot")
+	}
+	if found, _ := index.contains("OLD"); found {
+		t.Fatal("index kept the older snapshot")
+	}
+}
@@ -0,0 +1,116 @@
+package sanctions
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/warleon/ms4-compliance-service/internal/repository/rules"
+)
+
+// euExport mirrors the parts of the EU financial sanctions (FSF) XML export
+// that are kept. Elements are matched by local name, so the export namespace
+// does not need to be declared.
+type euExport struct {
+	Entities []euEntity `xml:"sanctionEntity"`
+}
+
+type euEntity struct {
+	LogicalID       string         `xml:"logicalId,attr"`
+	EUReference     string         `xml:"euReferenceNumber,attr"`
+	DesignationDate string         `xml:"designationDate,attr"`
+	Regulations     []euRegulation `xml:"regulation"`
+	SubjectType     euSubjectType  `xml:"subjectType"`
+	NameAliases     []euNameAlias  `xml:"nameAlias"`
+	Citizenships    []euCountryRef `xml:"citizenship"`
+	Addresses       []euCountryRef `xml:"address"`
+}
+
+type euRegulation struct {
+	Programme       string `xml:"programme,attr"`
+	PublicationDate string `xml:"publicationDate"`
+}
+
+type euSubjectType struct {
+	Code string `xml:"code,attr"`
+}
+
+type euNameAlias struct {
+	WholeName string `xml:"wholeName,attr"`
+	FirstName string `xml:"firstName,attr"`
+	LastName  string `xml:"lastName,attr"`
+}
+
+type euCountryRef struct {
+	CountryDescription string `xml:"countryDescription,attr"`
+	CountryISO2        string `xml:"countryIso2Code,attr"`
+}
+
+// ParseEU reads the EU consolidated financial sanctions list XML.
+func ParseEU(r io.Reader) ([]rules.Sanction, error) {
+	var e euExport
+	if err := xml.NewDecoder(r).Decode(&e); err != nil {
+		return nil, fmt.Errorf("eu: %w", err)
+	}
+	out := make([]rules.Sanction, 0, len(e.Entities))
+	for _, ent := range e.Entities {
+		out = append(out, ent.sanction())
+	}
+	return out, nil
+}
+
+func (e euEntity) sanction() rules.Sanction {
+	s := rules.Sanction{
+		PartyType:  e.SubjectType.Code,
+		Source:     rules.SanctionSourceEU,
+		ExternalID: strings.TrimSpace(e.LogicalID),
+	}
+	if s.ExternalID == "" {
+		s.ExternalID = strings.TrimSpace(e.EUReference)
+	}
+	switch s.PartyType {
+	case "person":
+		s.PartyType = "individual"
+	case "enterprise":
+		s.PartyType = "entity"
+	}
+	for _, a := range e.NameAliases {
+		name := strings.TrimSpace(a.WholeName)
+		if name == "" {
+			name = joinName(a.FirstName, a.LastName)
+		}
+		if s.Name == "" {
+			s.Name = name
+			continue
+		}
+		addAlias(&s, name)
+	}
+	var programs []string
+	for _, reg := range e.Regulations {
+		if p := strings.TrimSpace(reg.Programme); p != "" && !slices.Contains(programs, p) {
+			programs = append(programs, p)
+		}
+	}
+	s.Program = strings.Join(programs, ", ")
+	for _, c := range append(e.Citizenships, e.Addresses...) {
+		if v := strings.TrimSpace(c.CountryDescription); v != "" {
+			s.Country = v
+			break
+		}
+		if v := strings.TrimSpace(c.CountryISO2); v != "" {
+			s.Country = v
+			break
+		}
+	}
+	listed := strings.TrimSpace(e.DesignationDate)
+	if listed == "" && len(e.Regulations) > 0 {
+		listed = strings.TrimSpace(e.Regulations[0].PublicationDate)
+	}
+	if t, err := time.Parse("2006-01-02", listed); err == nil {
+		s.ListedAt = &t
+	}
+	return s
+}
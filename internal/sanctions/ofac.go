@@ -0,0 +1,119 @@
+package sanctions
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/warleon/ms4-compliance-service/internal/repository/rules"
+)
+
+// ofacNull is how the OFAC CSV files mark an empty field.
+const ofacNull = "-0-"
+
+// ParseOFAC reads the OFAC SDN list from its legacy CSV files. sdn (SDN.CSV)
+// is required; alt (ALT.CSV, aliases) and add (ADD.CSV, addresses) may be nil.
+// The files have no header row and are joined on their first column, ent_num.
+func ParseOFAC(sdn, alt, add io.Reader) ([]rules.Sanction, error) {
+	byEnt := make(map[string]*rules.Sanction)
+	var order []string
+
+	// SDN.CSV: ent_num, SDN_Name, SDN_Type, Program, Title, Call_Sign, Vess_type,
+	// Tonnage, GRT, Vess_flag, Vess_owner, Remarks
+	err := readOFAC(sdn, 4, func(rec []string) {
+		ent := rec[0]
+		s := &rules.Sanction{
+			Name:       rec[1],
+			PartyType:  ofacPartyType(rec[2]),
+			Program:    rec[3],
+			Source:     rules.SanctionSourceOFAC,
+			ExternalID: ent,
+		}
+		if len(rec) > 9 && s.PartyType == "vessel" {
+			s.Country = rec[9]
+		}
+		if _, ok := byEnt[ent]; !ok {
+			order = append(order, ent)
+		}
+		byEnt[ent] = s
+	})
+	if err != nil {
+		return nil, fmt.Errorf("sdn: %w", err)
+	}
+
+	// ALT.CSV: ent_num, alt_num, alt_type, alt_name, alt_remarks
+	if alt != nil {
+		err := readOFAC(alt, 4, func(rec []string) {
+			if s, ok := byEnt[rec[0]]; ok {
+				addAlias(s, rec[3])
+			}
+		})
+		if err != nil {
+			return nil, fmt.Errorf("alt: %w", err)
+		}
+	}
+
+	// ADD.CSV: ent_num, Add_num, Address, City/State/Province/Postal Code,
+	// Country, Add_remarks. The first address with a country wins.
+	if add != nil {
+		err := readOFAC(add, 5, func(rec []string) {
+			if s, ok := byEnt[rec[0]]; ok && s.Country == "" {
+				s.Country = rec[4]
+			}
+		})
+		if err != nil {
+			return nil, fmt.Errorf("add: %w", err)
+		}
+	}
+
+	out := make([]rules.Sanction, 0, len(order))
+	for _, ent := range order {
+		out = append(out, *byEnt[ent])
+	}
+	return out, nil
+}
+
+// readOFAC calls fn for every record with at least minFields fields, with
+// OFAC null markers replaced by empty strings. Blank trailing lines, which
+// the files end with, are skipped.
+func readOFAC(r io.Reader, minFields int, fn func(rec []string)) error {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if len(rec) < minFields {
+			continue
+		}
+		for i, f := range rec {
+			f = strings.TrimSpace(f)
+			if f == ofacNull {
+				f = ""
+			}
+			rec[i] = f
+		}
+		if rec[0] == "" {
+			continue
+		}
+		fn(rec)
+	}
+}
+
+func ofacPartyType(t string) string {
+	switch strings.ToLower(t) {
+	case "individual":
+		return "individual"
+	case "vessel":
+		return "vessel"
+	case "aircraft":
+		return "aircraft"
+	}
+	return "entity"
+}
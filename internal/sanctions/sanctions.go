@@ -0,0 +1,34 @@
+// Package sanctions parses official sanctions list publications into
+// rules.Sanction entries.
+package sanctions
+
+import (
+	"strings"
+
+	"github.com/warleon/ms4-compliance-service/internal/repository/rules"
+)
+
+// joinName builds a full name from its parts, skipping empty ones.
+func joinName(parts ...string) string {
+	var out []string
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return strings.Join(out, " ")
+}
+
+// addAlias appends alias to s unless it is empty or already the name or an alias.
+func addAlias(s *rules.Sanction, alias string) {
+	alias = strings.TrimSpace(alias)
+	if alias == "" || strings.EqualFold(alias, s.Name) {
+		return
+	}
+	for _, a := range s.Aliases {
+		if strings.EqualFold(a, alias) {
+			return
+		}
+	}
+	s.Aliases = append(s.Aliases, alias)
+}
@@ -0,0 +1,110 @@
+package sanctions
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/warleon/ms4-compliance-service/internal/repository/rules"
+)
+
+func open(t *testing.T, name string) io.Reader {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func date(s string) *time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return &t
+}
+
+func TestParse(t *testing.T) {
+	ofacSDN := []rules.Sanction{
+		{Name: "NORTHWIND TRADING LLC", PartyType: "entity", Program: "SDGT", Source: rules.SanctionSourceOFAC, ExternalID: "1001"},
+		{Name: "PETROV, Ivan Sergeyevich", PartyType: "individual", Program: "RUSSIA-EO14024", Source: rules.SanctionSourceOFAC, ExternalID: "1002"},
+		{Name: "SEA BREEZE", PartyType: "vessel", Program: "IRAN", Source: rules.SanctionSourceOFAC, ExternalID: "1003", Country: "Panama"},
+	}
+	ofacAll := []rules.Sanction{
+		{Name: "NORTHWIND TRADING LLC", PartyType: "entity", Program: "SDGT", Source: rules.SanctionSourceOFAC, ExternalID: "1001",
+			Aliases: []string{"NORTHWIND TRADING COMPANY"}, Country: "United Arab Emirates"},
+		{Name: "PETROV, Ivan Sergeyevich", PartyType: "individual", Program: "RUSSIA-EO14024", Source: rules.SanctionSourceOFAC, ExternalID: "1002",
+			Aliases: []string{"PETROFF, Ivan", "PIETROV, Iwan"}, Country: "Russia"},
+		ofacSDN[2],
+	}
+
+	for _, tc := range []struct {
+		name  string
+		parse func(t *testing.T) ([]rules.Sanction, error)
+		want  []rules.Sanction
+	}{
+		{"ofac sdn only", func(t *testing.T) ([]rules.Sanction, error) {
+			return ParseOFAC(open(t, "sdn.csv"), nil, nil)
+		}, ofacSDN},
+		{"ofac with aliases and addresses", func(t *testing.T) ([]rules.Sanction, error) {
+			return ParseOFAC(open(t, "sdn.csv"), open(t, "alt.csv"), open(t, "add.csv"))
+		}, ofacAll},
+		{"un", func(t *testing.T) ([]rules.Sanction, error) {
+			return ParseUN(open(t, "un_consolidated.xml"))
+		}, []rules.Sanction{
+			{Name: "JOSÉ ÁLVAREZ MORENO", PartyType: "individual", Program: "Al-Qaida", Source: rules.SanctionSourceUN, ExternalID: "6908001",
+				Aliases: []string{"Jose Alvarez", "El Moreno"}, Country: "Spain", ListedAt: date("2015-06-30")},
+			{Name: "EXAMPLE RELIEF FOUNDATION", PartyType: "entity", Program: "Al-Qaida", Source: rules.SanctionSourceUN, ExternalID: "6908002",
+				Aliases: []string{"ERF"}, Country: "Somalia", ListedAt: date("2016-02-11")},
+		}},
+		{"eu", func(t *testing.T) ([]rules.Sanction, error) {
+			return ParseEU(open(t, "eu_fsf.xml"))
+		}, []rules.Sanction{
+			{Name: "Olga Kuznetsova", PartyType: "individual", Program: "UKR", Source: rules.SanctionSourceEU, ExternalID: "9001",
+				Aliases: []string{"Ольга Кузнецова"}, Country: "RUSSIAN FEDERATION", ListedAt: date("2022-02-23")},
+			{Name: "Example Shipping JSC", PartyType: "entity", Program: "RUS", Source: rules.SanctionSourceEU, ExternalID: "9002",
+				Aliases: []string{"AO Example Shipping"}, Country: "RUSSIAN FEDERATION", ListedAt: date("2023-06-23")},
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.parse(t)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("got  %+v\nwant %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseMalformed(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		parse func() ([]rules.Sanction, error)
+	}{
+		{"ofac unreadable alt", func() ([]rules.Sanction, error) {
+			return ParseOFAC(strings.NewReader("1001,NAME,-0-,SDGT"), iotest.ErrReader(errors.New("read failed")), nil)
+		}},
+		{"un truncated", func() ([]rules.Sanction, error) {
+			return ParseUN(strings.NewReader("<CONSOLIDATED_LIST><INDIVIDUALS>"))
+		}},
+		{"eu not xml", func() ([]rules.Sanction, error) {
+			return ParseEU(strings.NewReader("ent_num,name"))
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got, err := tc.parse(); err == nil {
+				t.Fatalf("got %+v, want an error", got)
+			}
+		})
+	}
+}
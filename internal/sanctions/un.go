@@ -0,0 +1,92 @@
+package sanctions
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/warleon/ms4-compliance-service/internal/repository/rules"
+)
+
+// unList mirrors the parts of the UN Security Council consolidated list XML
+// (CONSOLIDATED_LIST) that are kept.
+type unList struct {
+	Individuals []unParty `xml:"INDIVIDUALS>INDIVIDUAL"`
+	Entities    []unParty `xml:"ENTITIES>ENTITY"`
+}
+
+type unParty struct {
+	DataID        string      `xml:"DATAID"`
+	FirstName     string      `xml:"FIRST_NAME"`
+	SecondName    string      `xml:"SECOND_NAME"`
+	ThirdName     string      `xml:"THIRD_NAME"`
+	FourthName    string      `xml:"FOURTH_NAME"`
+	ListType      string      `xml:"UN_LIST_TYPE"`
+	Reference     string      `xml:"REFERENCE_NUMBER"`
+	ListedOn      string      `xml:"LISTED_ON"`
+	Nationalities []string    `xml:"NATIONALITY>VALUE"`
+	Aliases       []unAlias   `xml:"INDIVIDUAL_ALIAS"`
+	EntityAliases []unAlias   `xml:"ENTITY_ALIAS"`
+	Addresses     []unAddress `xml:"INDIVIDUAL_ADDRESS"`
+	EntityAddrs   []unAddress `xml:"ENTITY_ADDRESS"`
+}
+
+type unAlias struct {
+	Name string `xml:"ALIAS_NAME"`
+}
+
+type unAddress struct {
+	Country string `xml:"COUNTRY"`
+}
+
+// ParseUN reads the UN Security Council consolidated sanctions list XML.
+func ParseUN(r io.Reader) ([]rules.Sanction, error) {
+	var l unList
+	if err := xml.NewDecoder(r).Decode(&l); err != nil {
+		return nil, fmt.Errorf("un: %w", err)
+	}
+	out := make([]rules.Sanction, 0, len(l.Individuals)+len(l.Entities))
+	for _, p := range l.Individuals {
+		out = append(out, p.sanction("individual"))
+	}
+	for _, p := range l.Entities {
+		out = append(out, p.sanction("entity"))
+	}
+	return out, nil
+}
+
+func (p unParty) sanction(partyType string) rules.Sanction {
+	s := rules.Sanction{
+		Name:       joinName(p.FirstName, p.SecondName, p.ThirdName, p.FourthName),
+		PartyType:  partyType,
+		Program:    strings.TrimSpace(p.ListType),
+		Source:     rules.SanctionSourceUN,
+		ExternalID: strings.TrimSpace(p.DataID),
+	}
+	if s.ExternalID == "" {
+		s.ExternalID = strings.TrimSpace(p.Reference)
+	}
+	for _, a := range append(p.Aliases, p.EntityAliases...) {
+		addAlias(&s, a.Name)
+	}
+	for _, n := range p.Nationalities {
+		if n = strings.TrimSpace(n); n != "" {
+			s.Country = n
+			break
+		}
+	}
+	if s.Country == "" {
+		for _, a := range append(p.Addresses, p.EntityAddrs...) {
+			if c := strings.TrimSpace(a.Country); c != "" {
+				s.Country = c
+				break
+			}
+		}
+	}
+	if t, err := time.Parse("2006-01-02", strings.TrimSpace(p.ListedOn)); err == nil {
+		s.ListedAt = &t
+	}
+	return s
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/warleon/ms4-compliance-service/internal/dto"
+	"github.com/warleon/ms4-compliance-service/internal/money"
+	"github.com/warleon/ms4-compliance-service/internal/repository"
+	"github.com/warleon/ms4-compliance-service/internal/repository/rules"
+)
+
+// auditRepo is an in-memory audit log that chains entries as CreateAudit
+// does and returns them as the database reads them back.
+type auditRepo struct {
+	repository.Repository
+	audits []repository.AuditLog
+	head   repository.AuditChainHead
+}
+
+func (r *auditRepo) CreateAudit(a *repository.AuditLog) error {
+	a.ID = uint(len(r.audits) + 1)
+	a.PrevHash = r.head.Hash
+	a.CreatedAt = time.Now().Truncate(time.Millisecond)
+	var err error
+	if a.Hash, err = a.ComputeHash(); err != nil {
+		return err
+	}
+	r.audits = append(r.audits, *a)
+	r.head.AuditID, r.head.Hash = a.ID, a.Hash
+	return nil
+}
+
+func (r *auditRepo) ScanAudits(afterID uint, size int) ([]repository.AuditLog, error) {
+	var out []repository.AuditLog
+	for _, a := range r.audits {
+		if a.ID <= afterID || len(out) == size {
+			continue
+		}
+		// the JSON columns come back as plain JSON values
+		for _, v := range []any{&a.Transaction, &a.Trace} {
+			b, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			if err := json.Unmarshal(b, v); err != nil {
+				return nil, err
+			}
+		}
+		out = append(out, a)
+	}
+	return out, nil
+}
+
+func (r *auditRepo) ReadAuditChainHead() (*repository.AuditChainHead, error) {
+	head := r.head
+	return &head, nil
+}
+
+// chainedAudits returns a repository holding n audits of name screening
+// decisions, whose trace inputs change type when read back.
+func chainedAudits(t *testing.T, n int) *auditRepo {
+	t.Helper()
+	r := &auditRepo{}
+	for i := range n {
+		match := rules.NameMatch{SanctionID: uint(i), Name: "John Doe", MatchedName: "John Doe", Score: 0.9}
+		a := repository.AuditLog{
+			TransactionID: "tx",
+			Decision:      rules.NewDecision(rules.StatusManualReview, "Party name resembles sanctioned party John Doe"),
+			Transaction:   dto.Transaction{ID: "tx", Amount: money.MustParse("922337203685.4775"), Metadata: map[string]any{"partyName": "Jon Doe"}},
+			Trace: []rules.RuleResult{{
+				RuleID: 1, Type: rules.RuleTypeNameScreening, Status: rules.StatusManualReview,
+				Inputs: map[string]any{"matches": []rules.NameMatch{match}, "threshold": 0.85},
+			}},
+		}
+		if err := r.CreateAudit(&a); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return r
+}
+
+func TestVerifyAuditChain(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		tamper   func(r *auditRepo)
+		brokenAt uint
+	}{
+		{"intact", func(r *auditRepo) {}, 0},
+		{"deleted entry", func(r *auditRepo) {
+			r.audits = append(r.audits[:1], r.audits[2:]...)
+		}, 3},
+		{"soft deleted entry", func(r *auditRepo) {
+			r.audits[1].DeletedAt.Valid = true
+		}, 2},
+		{"edited entry", func(r *auditRepo) {
+			r.audits[1].Decision.Status = rules.StatusApprove
+		}, 2},
+		{"edited head", func(r *auditRepo) {
+			r.head.Hash = r.audits[1].Hash
+		}, 3},
+		{"last entry removed", func(r *auditRepo) {
+			r.audits = r.audits[:2]
+		}, 3},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := chainedAudits(t, 3)
+			tc.tamper(r)
+			rep, err := NewComplianceService(r).VerifyAuditChain(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rep.Valid != (tc.brokenAt == 0) || rep.BrokenAt != tc.brokenAt {
+				t.Fatalf("report = %+v, want broken at %d", rep, tc.brokenAt)
+			}
+		})
+	}
+}
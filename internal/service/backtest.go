@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/warleon/ms4-compliance-service/internal/repository"
+	"github.com/warleon/ms4-compliance-service/internal/repository/rules"
+)
+
+// maxBacktestSamples caps the changed decisions a backtest lists per outcome pair.
+const maxBacktestSamples = 10
+
+// ErrInvalidBacktest is returned for a backtest with an unusable time range.
+var ErrInvalidBacktest = errors.New("invalid backtest")
+
+// Backtest is a candidate rule set to replay over the audit history.
+//
+// Rules are complete rule definitions: one with the ID of a stored rule
+// replaces it, one without an ID is added. Remove lists stored rules to leave
+// out. The remaining stored rules take part unchanged.
+type Backtest struct {
+	From   time.Time      `json:"from"`
+	To     time.Time      `json:"to"`
+	Rules  []rules.Rule   `json:"rules"`
+	Remove []uint         `json:"remove,omitempty"`
+	Mode   EvaluationMode `json:"mode,omitempty"`
+}
+
+// BacktestReport compares the decisions recorded in [From, To) with those the
+// candidate rule set would have made. Comparisons counts each pair of actual
+// and candidate outcome; Changed counts the transactions whose outcome
+// differs.
+type BacktestReport struct {
+	From        time.Time            `json:"from"`
+	To          time.Time            `json:"to"`
+	Audits      int                  `json:"audits"`
+	Changed     int                  `json:"changed"`
+	Comparisons []BacktestComparison `json:"comparisons"`
+}
+
+// BacktestComparison counts transactions with a given actual and candidate
+// outcome. For changed outcomes, Samples lists some of them.
+type BacktestComparison struct {
+	Actual    rules.DecisionStatus `json:"actual"`
+	Candidate rules.DecisionStatus `json:"candidate"`
+	Count     int                  `json:"count"`
+	Samples   []BacktestSample     `json:"samples,omitempty"`
+}
+
+// BacktestSample is one transaction whose outcome would have changed, with
+// the candidate decision's reason and risk score.
+type BacktestSample struct {
+	AuditID       uint    `json:"auditId"`
+	TransactionID string  `json:"transactionId"`
+	Reason        string  `json:"reason"`
+	RiskScore     float64 `json:"riskScore"`
+}
+
+// Backtest replays the transactions audited in [b.From, b.To) through the
+// candidate rule set and reports how the decisions would have changed.
+// Nothing is written. Each transaction is evaluated with the rules in effect
+// at its own time and the transaction history recorded before its audit
+// entry, so transactions that arrived later are left out. Sanctions lists are
+// taken as they are now, so sanctions results are approximate.
+func (s *ComplianceService) Backtest(ctx context.Context, b Backtest) (*BacktestReport, error) {
+	if b.From.IsZero() || b.To.IsZero() || !b.From.Before(b.To) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidBacktest)
+	}
+	if b.Mode == "" {
+		b.Mode = s.DefaultMode
+	}
+	if !b.Mode.Valid() {
+		return nil, ErrInvalidMode
+	}
+	snap, err := loadSnapshot(s.Repo)
+	if err != nil {
+		return nil, err
+	}
+	if snap.rules, err = candidateRules(snap.rules, b); err != nil {
+		return nil, err
+	}
+
+	report := &BacktestReport{From: b.From, To: b.To, Comparisons: []BacktestComparison{}}
+	pairs := make(map[[2]rules.DecisionStatus]*BacktestComparison)
+	f := repository.AuditFilter{From: &b.From, To: &b.To, Size: 500}
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		page, err := s.Repo.FindAudits(f)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range page {
+			in := a.Transaction
+			if in.Timestamp.IsZero() {
+				in.Timestamp = a.CreatedAt
+			}
+			env := snap.env(s.Repo)
+			env.at = a.CreatedAt
+			dec, trace, err := evaluate(env, snap.rules, in, b.Mode)
+			if err != nil {
+				return nil, err
+			}
+			s.score(dec, trace)
+
+			report.Audits++
+			key := [2]rules.DecisionStatus{actualStatus(a.Decision), dec.Status}
+			cmp, ok := pairs[key]
+			if !ok {
+				cmp = &BacktestComparison{Actual: key[0], Candidate: key[1]}
+				pairs[key] = cmp
+			}
+			cmp.Count++
+			if key[0] == key[1] {
+				continue
+			}
+			report.Changed++
+			if len(cmp.Samples) < maxBacktestSamples {
+				cmp.Samples = append(cmp.Samples, BacktestSample{
+					AuditID:       a.ID,
+					TransactionID: a.TransactionID,
+					Reason:        dec.Reason,
+					RiskScore:     dec.RiskScore,
+				})
+			}
+		}
+		if len(page) < f.Size {
+			break
+		}
+		f.Cursor = page[len(page)-1].ID
+	}
+	for _, cmp := range pairs {
+		report.Comparisons = append(report.Comparisons, *cmp)
+	}
+	sort.Slice(report.Comparisons, func(i, j int) bool {
+		a, b := report.Comparisons[i], report.Comparisons[j]
+		if a.Actual != b.Actual {
+			return a.Actual < b.Actual
+		}
+		return a.Candidate < b.Candidate
+	})
+	return report, nil
+}
+
+// candidateRules applies the changes in b to the stored rules. Added rules get
+// IDs above the highest stored one.
+func candidateRules(stored []rules.Rule, b Backtest) ([]rules.Rule, error) {
+	byID := make(map[uint]int, len(stored))
+	var next uint
+	for i, r := range stored {
+		byID[r.ID] = i
+		next = max(next, r.ID)
+	}
+	out := append([]rules.Rule(nil), stored...)
+	for i, r := range b.Rules {
+		if err := r.Check(); err != nil {
+			return nil, fmt.Errorf("%w: rule %d: %v", ErrInvalidRule, i+1, err)
+		}
+		if r.ID == 0 {
+			next++
+			r.ID = next
+			out = append(out, r)
+			continue
+		}
+		j, ok := byID[r.ID]
+		if !ok {
+			return nil, fmt.Errorf("%w: %d", ErrRuleNotFound, r.ID)
+		}
+		out[j] = r
+	}
+	if len(b.Remove) > 0 {
+		remove := make(map[uint]bool, len(b.Remove))
+		for _, id := range b.Remove {
+			if _, ok := byID[id]; !ok {
+				return nil, fmt.Errorf("%w: %d", ErrRuleNotFound, id)
+			}
+			remove[id] = true
+		}
+		kept := out[:0]
+		for _, r := range out {
+			if !remove[r.ID] {
+				kept = append(kept, r)
+			}
+		}
+		out = kept
+	}
+	return out, nil
+}
+
+// actualStatus returns the status of a recorded decision. Decisions stored
+// before statuses existed only carry Approved.
+func actualStatus(d rules.Decision) rules.DecisionStatus {
+	if d.Status != "" {
+		return d.Status
+	}
+	if d.Approved {
+		return rules.StatusApprove
+	}
+	return rules.StatusReject
+}
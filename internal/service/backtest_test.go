@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/warleon/ms4-compliance-service/internal/dto"
+	"github.com/warleon/ms4-compliance-service/internal/money"
+	"github.com/warleon/ms4-compliance-service/internal/repository"
+	"github.com/warleon/ms4-compliance-service/internal/repository/rules"
+)
+
+// historyRepo holds audited transactions and the history they left behind.
+type historyRepo struct {
+	repository.Repository
+	audits  []repository.AuditLog // newest first
+	records []repository.TransactionRecord
+}
+
+func (r *historyRepo) FindRules() ([]rules.Rule, error)                 { return nil, nil }
+func (r *historyRepo) FindSanctionedParties() ([]rules.Sanction, error) { return nil, nil }
+func (r *historyRepo) ListSanctionedAccounts() ([]string, error)        { return nil, nil }
+
+func (r *historyRepo) FindAudits(f repository.AuditFilter) ([]repository.AuditLog, error) {
+	return r.audits, nil
+}
+
+func (r *historyRepo) TransactionStats(f repository.TransactionFilter) (int64, money.Amount, error) {
+	var n int64
+	var sum money.Amount
+	for _, rec := range r.records {
+		if rec.FromAcc != f.Value || rec.CreatedAt.Before(f.Since) ||
+			(!f.Until.IsZero() && !rec.CreatedAt.Before(f.Until)) {
+			continue
+		}
+		n++
+		sum = sum.Add(rec.Amount)
+	}
+	return n, sum, nil
+}
+
+func TestBacktestReadsHistoryAsAudited(t *testing.T) {
+	t0 := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+	repo := &historyRepo{}
+	// three transfers from X, audited at t0, t0+10m and t0+2h; their own
+	// timestamps are a day earlier, as for transactions submitted late
+	for i, at := range []time.Time{t0.Add(2 * time.Hour), t0.Add(10 * time.Minute), t0} {
+		a := repository.AuditLog{
+			Decision:    rules.Approve(),
+			Transaction: dto.Transaction{FromAcc: "X", ToAcc: "Y", Amount: money.MustParse("10"), Timestamp: at.Add(-24 * time.Hour)},
+		}
+		a.ID, a.CreatedAt = uint(3-i), at
+		repo.audits = append(repo.audits, a)
+		rec := repository.TransactionRecord{FromAcc: "X", ToAcc: "Y", Amount: money.MustParse("10")}
+		rec.CreatedAt = at
+		repo.records = append(repo.records, rec)
+	}
+
+	window, maxCount, key := "1h", int64(1), rules.VelocityKeyFromAcc
+	velocity := rules.Rule{RuleExtras: rules.RuleExtras{Window: &window, MaxCount: &maxCount, KeyField: &key}}
+	velocity.Name, velocity.Type = "one an hour", rules.RuleTypeVelocity
+
+	rep, err := NewComplianceService(repo).Backtest(context.Background(), Backtest{
+		From:  t0.Add(-time.Hour),
+		To:    t0.Add(3 * time.Hour),
+		Rules: []rules.Rule{velocity},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// only the second transfer had one before it within the hour; neither
+	// the transfer itself nor the later one counts
+	if rep.Audits != 3 || rep.Changed != 1 {
+		t.Fatalf("report = %+v, want 3 audits with 1 changed", rep)
+	}
+	for _, c := range rep.Comparisons {
+		if c.Candidate == rules.StatusReject && (c.Count != 1 || c.Samples[0].AuditID != 2) {
+			t.Fatalf("rejections = %+v, want audit 2 only", c)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/warleon/ms4-compliance-service/internal/dto"
+	"github.com/warleon/ms4-compliance-service/internal/repository"
+	"github.com/warleon/ms4-compliance-service/internal/repository/rules"
+)
+
+// Default batch settings of a new ComplianceService.
+const (
+	DefaultBatchMaxSize = 1000
+	DefaultBatchWorkers = 8
+)
+
+var (
+	ErrInvalidBatch  = errors.New("invalid batch")
+	ErrEmptyBatch    = errors.New("batch has no transactions")
+	ErrBatchTooLarge = errors.New("batch has too many transactions")
+)
+
+// BatchResult is the outcome of one transaction in a batch. Error is set
+// instead of Decision when the transaction could not be validated.
+type BatchResult struct {
+	TransactionID string          `json:"transactionId"`
+	Decision      *rules.Decision `json:"decision,omitempty"`
+	Error         string          `json:"error,omitempty"`
+}
+
+// ReadTransactions decodes transactions from a JSON array or from NDJSON (one
+// transaction per line). It stops with ErrBatchTooLarge once more than max
+// transactions have been read.
+func ReadTransactions(r io.Reader, max int) ([]dto.Transaction, error) {
+	br := bufio.NewReader(r)
+	array, err := startsWithArray(br)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidBatch, err)
+	}
+	dec := json.NewDecoder(br)
+	if array {
+		if _, err := dec.Token(); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidBatch, err)
+		}
+	}
+
+	var out []dto.Transaction
+	for {
+		if array && !dec.More() {
+			break
+		}
+		var tx dto.Transaction
+		err := dec.Decode(&tx)
+		if err == io.EOF && !array {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: transaction %d: %w", ErrInvalidBatch, len(out)+1, err)
+		}
+		if len(out) == max {
+			return nil, fmt.Errorf("%w: the limit is %d", ErrBatchTooLarge, max)
+		}
+		out = append(out, tx)
+	}
+	if array {
+		if _, err := dec.Token(); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidBatch, err)
+		}
+	}
+	if len(out) == 0 {
+		return nil, ErrEmptyBatch
+	}
+	return out, nil
+}
+
+// startsWithArray skips leading whitespace and reports whether the input is a
+// JSON array.
+func startsWithArray(br *bufio.Reader) (bool, error) {
+	for {
+		b, err := br.ReadByte()
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return b == '[', br.UnreadByte()
+	}
+}
+
+// ValidateTransactions validates a batch of transactions with a bounded pool
+// of workers and returns their results in input order. Rules and sanctions are
+// loaded once for the whole batch. Each transaction is validated and audited
+// on its own, exactly as ValidateTransaction would, so a failure is reported
+// in its result and does not affect the others. Transactions that share an
+// account or customer are validated one after another, in input order, so
+// velocity and structuring rules see the earlier ones as history.
+func (s *ComplianceService) ValidateTransactions(ctx context.Context, txs []dto.Transaction, mode EvaluationMode) ([]BatchResult, error) {
+	return s.validateBatch(ctx, txs, mode, nil)
+}
+
+// validateBatch validates txs as ValidateTransactions describes. done, when
+// set, is called with the index and decision of each transaction in the
+// database transaction that audits it.
+func (s *ComplianceService) validateBatch(ctx context.Context, txs []dto.Transaction, mode EvaluationMode, done func(int, repository.Repository, *rules.Decision) error) ([]BatchResult, error) {
+	if mode == "" {
+		mode = s.DefaultMode
+	}
+	if !mode.Valid() {
+		return nil, ErrInvalidMode
+	}
+	if len(txs) == 0 {
+		return nil, ErrEmptyBatch
+	}
+	if len(txs) > s.BatchMaxSize {
+		return nil, fmt.Errorf("%w: the limit is %d", ErrBatchTooLarge, s.BatchMaxSize)
+	}
+	snap, err := loadSnapshot(s.Repo)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]BatchResult, len(txs))
+	groups := batchGroups(txs)
+	next := make(chan []int)
+	var wg sync.WaitGroup
+	for range min(max(s.BatchWorkers, 1), len(groups)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for group := range next {
+				for _, i := range group {
+					out[i].TransactionID = txs[i].ID
+					if err := ctx.Err(); err != nil {
+						out[i].Error = err.Error()
+						continue
+					}
+					var hook func(repository.Repository, *rules.Decision) error
+					if done != nil {
+						hook = func(repo repository.Repository, dec *rules.Decision) error {
+							return done(i, repo, dec)
+						}
+					}
+					dec, err := s.validate(txs[i], mode, snap, hook)
+					if err != nil {
+						out[i].Error = err.Error()
+						continue
+					}
+					out[i].Decision = dec
+				}
+			}
+		}()
+	}
+	for _, group := range groups {
+		next <- group
+	}
+	close(next)
+	wg.Wait()
+	return out, nil
+}
+
+// batchGroups splits txs into groups of transactions linked by a shared
+// source account, destination account or customer, the fields history is
+// counted by. Groups hold input indexes in order and come in the order of
+// their first transaction.
+func batchGroups(txs []dto.Transaction) [][]int {
+	parent := make([]int, len(txs))
+	for i := range parent {
+		parent[i] = i
+	}
+	find := func(i int) int {
+		for parent[i] != i {
+			parent[i] = parent[parent[i]]
+			i = parent[i]
+		}
+		return i
+	}
+	first := make(map[[2]string]int)
+	for i, tx := range txs {
+		for _, key := range []rules.VelocityKey{rules.VelocityKeyFromAcc, rules.VelocityKeyToAcc, rules.VelocityKeyCustomerID} {
+			v := key.Value(tx)
+			if v == "" {
+				continue
+			}
+			k := [2]string{string(key), v}
+			if j, ok := first[k]; ok {
+				parent[find(i)] = find(j)
+			} else {
+				first[k] = i
+			}
+		}
+	}
+
+	var groups [][]int
+	index := make(map[int]int)
+	for i := range txs {
+		root := find(i)
+		g, ok := index[root]
+		if !ok {
+			g = len(groups)
+			index[root] = g
+			groups = append(groups, nil)
+		}
+		groups[g] = append(groups[g], i)
+	}
+	return groups
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/warleon/ms4-compliance-service/internal/dto"
+)
+
+func TestBatchGroups(t *testing.T) {
+	txs := []dto.Transaction{
+		{FromAcc: "A", ToAcc: "B"},
+		{FromAcc: "C", ToAcc: "D"},
+		{FromAcc: "E", ToAcc: "B"}, // shares B with 0
+		{FromAcc: "F", ToAcc: "G", CustomerID: "X"},
+		{FromAcc: "C", ToAcc: "H"},                  // shares C with 1
+		{FromAcc: "I", ToAcc: "J", CustomerID: "X"}, // shares X with 3
+		{FromAcc: "B", ToAcc: "K"},                  // B as a source is a different key
+		{},
+	}
+	want := [][]int{{0, 2}, {1, 4}, {3, 5}, {6}, {7}}
+	if got := batchGroups(txs); !reflect.DeepEqual(got, want) {
+		t.Fatalf("batchGroups = %v, want %v", got, want)
+	}
+}
+
+func TestReadTransactions(t *testing.T) {
+	for _, tc := range []struct {
+		name, in string
+		n        int
+		err      error
+	}{
+		{"array", `[{"id":"1"},{"id":"2"}]`, 2, nil},
+		{"ndjson", "{\"id\":\"1\"}\n\n{\"id\":\"2\"}\n", 2, nil},
+		{"empty array", `[]`, 0, ErrEmptyBatch},
+		{"too many", `[{},{},{}]`, 0, ErrBatchTooLarge},
+		{"malformed", `[{"id":}]`, 0, ErrInvalidBatch},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			txs, err := ReadTransactions(strings.NewReader(tc.in), 2)
+			if tc.err != nil {
+				if !errors.Is(err, tc.err) {
+					t.Fatalf("err = %v, want %v", err, tc.err)
+				}
+				return
+			}
+			if err != nil || len(txs) != tc.n {
+				t.Fatalf("got %d transactions, err %v; want %d", len(txs), err, tc.n)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/warleon/ms4-compliance-service/internal/dto"
+	"github.com/warleon/ms4-compliance-service/internal/notify"
+	"github.com/warleon/ms4-compliance-service/internal/repository"
+	"github.com/warleon/ms4-compliance-service/internal/repository/rules"
+	"gorm.io/gorm"
+)
+
+// CallbackMetadataKey is the transaction metadata entry holding the URL that is
+// notified when a review case for the transaction is resolved.
+const CallbackMetadataKey = "callbackUrl"
+
+var (
+	ErrCaseNotFound = errors.New("case not found")
+	ErrCaseClosed   = errors.New("case is already closed")
+	ErrCaseAction   = errors.New("invalid case action")
+)
+
+// CaseOutcome is the payload posted to a case's callback URL once it is resolved.
+type CaseOutcome struct {
+	CaseID        uint                 `json:"caseId"`
+	AuditID       uint                 `json:"auditId"`
+	TransactionID string               `json:"transactionId"`
+	Status        rules.DecisionStatus `json:"status"`
+	ResolvedBy    string               `json:"resolvedBy"`
+	ResolvedAt    time.Time            `json:"resolvedAt"`
+	Comment       string               `json:"comment,omitempty"`
+}
+
+// openCase queues a manual_review decision for an analyst, to be reported to
+// callback when resolved. It runs inside the validation transaction, right
+// after the audit entry is written.
+func openCase(repo repository.Repository, audit *repository.AuditLog, callback string) (*repository.Case, error) {
+	c := &repository.Case{
+		AuditID:       audit.ID,
+		TransactionID: audit.TransactionID,
+		CustomerID:    audit.CustomerID,
+		Status:        repository.CaseOpen,
+		Reason:        audit.Decision.Reason,
+		CallbackURL:   callback,
+	}
+	if err := repo.CreateCase(c); err != nil {
+		return nil, err
+	}
+	err := repo.CreateAuditEvent(&repository.AuditEvent{
+		AuditID: audit.ID,
+		CaseID:  c.ID,
+		Action:  repository.EventOpened,
+		Status:  c.Status,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// callbackURL returns the transaction's callback URL if its host is on the
+// callback allowlist.
+func (s *ComplianceService) callbackURL(tx dto.Transaction) string {
+	raw, _ := tx.Metadata[CallbackMetadataKey].(string)
+	if raw == "" {
+		return ""
+	}
+	if !s.CallbackHosts.Allows(raw) {
+		logrus.WithField("url", raw).Warn("ignoring callback URL whose host is not allowed")
+		return ""
+	}
+	return raw
+}
+
+// ListCases returns a page of review cases matching the filter, oldest first.
+func (s *ComplianceService) ListCases(ctx context.Context, f repository.CaseFilter) ([]repository.Case, error) {
+	return s.Repo.FindCases(f)
+}
+
+// GetCase returns a review case with its history. Callers poll it to learn
+// the final outcome.
+func (s *ComplianceService) GetCase(ctx context.Context, id uint) (*repository.Case, error) {
+	c, err := s.Repo.ReadCase(id)
+	if err == gorm.ErrRecordNotFound {
+		return nil, ErrCaseNotFound
+	}
+	return c, err
+}
+
+// AssignCase hands an open case to an analyst, or reassigns it.
+func (s *ComplianceService) AssignCase(ctx context.Context, id uint, in dto.CaseAction) (*repository.Case, error) {
+	if in.Assignee == "" {
+		return nil, fmt.Errorf("%w: assignee is required", ErrCaseAction)
+	}
+	return s.changeCase(id, in, repository.EventAssigned, func(c *repository.Case) {
+		c.Status = repository.CaseAssigned
+		c.Assignee = in.Assignee
+	})
+}
+
+// CommentCase adds an analyst note to an open case.
+func (s *ComplianceService) CommentCase(ctx context.Context, id uint, in dto.CaseAction) (*repository.Case, error) {
+	if in.Comment == "" {
+		return nil, fmt.Errorf("%w: comment is required", ErrCaseAction)
+	}
+	return s.changeCase(id, in, repository.EventCommented, func(*repository.Case) {})
+}
+
+// ResolveCase closes a case as approved or rejected and notifies its callback
+// URL, if its host is still allowed.
+func (s *ComplianceService) ResolveCase(ctx context.Context, id uint, approve bool, in dto.CaseAction) (*repository.Case, error) {
+	action, status := repository.EventRejected, repository.CaseRejected
+	if approve {
+		action, status = repository.EventApproved, repository.CaseApproved
+	}
+	now := time.Now()
+	c, err := s.changeCase(id, in, action, func(c *repository.Case) {
+		c.Status = status
+		c.ResolvedBy = in.Actor
+		c.ResolvedAt = &now
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if c.CallbackURL != "" && s.CallbackHosts.Allows(c.CallbackURL) {
+		out := CaseOutcome{
+			CaseID:        c.ID,
+			AuditID:       c.AuditID,
+			TransactionID: c.TransactionID,
+			Status:        rules.StatusReject,
+			ResolvedBy:    c.ResolvedBy,
+			ResolvedAt:    now,
+			Comment:       in.Comment,
+		}
+		if approve {
+			out.Status = rules.StatusApprove
+		}
+		notify.Post(c.CallbackURL, out)
+	}
+	return c, nil
+}
+
+// changeCase applies a state change to an open case and records it as an
+// event on the case's audit entry, in one transaction.
+func (s *ComplianceService) changeCase(id uint, in dto.CaseAction, action string, apply func(c *repository.Case)) (*repository.Case, error) {
+	err := s.Repo.WithTx(func(repo repository.Repository) error {
+		c, err := repo.LockCase(id)
+		if err == gorm.ErrRecordNotFound {
+			return ErrCaseNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if c.Status.Closed() {
+			return ErrCaseClosed
+		}
+		apply(c)
+		if err := repo.UpdateCase(c); err != nil {
+			return err
+		}
+		return repo.CreateAuditEvent(&repository.AuditEvent{
+			AuditID: c.AuditID,
+			CaseID:  c.ID,
+			Action:  action,
+			Actor:   in.Actor,
+			Comment: in.Comment,
+			Status:  c.Status,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Repo.ReadCase(id)
+}
@@ -2,66 +2,348 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"time"
 
 	"github.com/warleon/ms4-compliance-service/internal/dto"
+	"github.com/warleon/ms4-compliance-service/internal/money"
+	"github.com/warleon/ms4-compliance-service/internal/notify"
 	"github.com/warleon/ms4-compliance-service/internal/repository"
 	"github.com/warleon/ms4-compliance-service/internal/repository/rules"
 )
 
+// EvaluationMode controls whether validation stops at the first reject.
+type EvaluationMode string
+
+const (
+	// ModeAll runs every applicable rule and reports every violation.
+	ModeAll EvaluationMode = "all"
+	// ModeFirstFailure stops at the first rule that rejects.
+	ModeFirstFailure EvaluationMode = "first_failure"
+)
+
+// Valid reports whether m is a known mode.
+func (m EvaluationMode) Valid() bool {
+	return m == ModeAll || m == ModeFirstFailure
+}
+
+// ErrInvalidMode is returned for an unknown evaluation mode.
+var ErrInvalidMode = errors.New("invalid evaluation mode, use all or first_failure")
+
+// ErrInvalidAmount is returned for an amount with more decimal places than
+// its currency has minor units.
+var ErrInvalidAmount = errors.New("invalid amount")
+
 // ComplianceService contains business logic.
 type ComplianceService struct {
 	Repo repository.Repository
+	// DefaultMode is used when a validation request does not choose a mode.
+	DefaultMode EvaluationMode
+	// Bands map the risk score to a decision status.
+	Bands rules.RiskBands
+	// IdempotencyRetention is how long a validated transaction ID replays
+	// its stored decision. Zero turns replays off.
+	IdempotencyRetention time.Duration
+	// BatchMaxSize caps the transactions in one batch validation, which
+	// BatchWorkers validate concurrently.
+	BatchMaxSize int
+	BatchWorkers int
+	// JobMaxItems caps the transactions or accounts in one job file.
+	JobMaxItems int
+	// CallbackHosts are the hosts review case and job callbacks may be
+	// sent to.
+	CallbackHosts notify.Allowlist
+
+	jobWake  chan struct{}
+	jobOwner string // identifies this process when claiming jobs
 }
 
 func NewComplianceService(repo repository.Repository) *ComplianceService {
-	return &ComplianceService{Repo: repo}
+	return &ComplianceService{
+		Repo:         repo,
+		DefaultMode:  ModeAll,
+		Bands:        rules.DefaultRiskBands,
+		BatchMaxSize: DefaultBatchMaxSize,
+		BatchWorkers: DefaultBatchWorkers,
+		JobMaxItems:  DefaultJobMaxItems,
+		jobWake:      make(chan struct{}, 1),
+		jobOwner:     processID(),
+	}
+}
+
+// processID returns an identifier unique to this process, made of the host
+// name, the process ID and a random suffix.
+func processID() string {
+	host, _ := os.Hostname()
+	b := make([]byte, 4)
+	rand.Read(b)
+	return fmt.Sprintf("%s-%d-%x", host, os.Getpid(), b)
+}
+
+// ValidateTransaction evaluates in against the configured rules and writes an
+// audit entry with the decision and its trace. Both happen in one database
+// transaction, so a decision is never returned without its audit record.
+// Decisions sent to manual review also open a review case. An empty mode uses
+// the service's DefaultMode.
+//
+// A transaction ID validated within the retention period is not evaluated
+// again: the stored decision is returned with Replayed set, or
+// ErrIdempotencyConflict if the payload differs from the first request.
+func (s *ComplianceService) ValidateTransaction(ctx context.Context, in dto.Transaction, mode EvaluationMode) (*rules.Decision, error) {
+	if mode == "" {
+		mode = s.DefaultMode
+	}
+	if !mode.Valid() {
+		return nil, ErrInvalidMode
+	}
+	return s.validate(in, mode, nil, nil)
 }
 
-func (s *ComplianceService) ValidateTransaction(ctx context.Context, in dto.Transaction) (*rules.Decision, error) {
-	// 1) Evaluate amount threshold rules
-	amtRules, err := s.Repo.FindRulesByType(string(rules.RuleTypeAmountThreshold))
+// validate evaluates in and stores the decision as ValidateTransaction
+// describes. Rules and sanctions come from snap, or from the repository when
+// snap is nil. done, when set, is called with the decision in the same
+// database transaction as the audit entry, which is rolled back if it fails.
+func (s *ComplianceService) validate(in dto.Transaction, mode EvaluationMode, snap *snapshot, done func(repository.Repository, *rules.Decision) error) (*rules.Decision, error) {
+	if !in.Amount.Fits(in.Currency) {
+		return nil, fmt.Errorf("%w: %s %s has more than %d decimal places", ErrInvalidAmount, in.Amount, in.Currency, money.MinorUnits(in.Currency))
+	}
+	idempotent := in.ID != "" && s.IdempotencyRetention > 0
+	var hash string
+	if idempotent {
+		// Hash the payload as sent, before defaults are filled in
+		var err error
+		if hash, err = payloadHash(in); err != nil {
+			return nil, err
+		}
+	}
+	if in.Timestamp.IsZero() {
+		in.Timestamp = time.Now()
+	}
+	var out *rules.Decision
+	var trace []rules.RuleResult
+	err := s.Repo.WithTx(func(repo repository.Repository) error {
+		var key *repository.IdempotencyKey
+		if idempotent {
+			k, prev, err := s.claimTransaction(repo, in, hash)
+			if err != nil {
+				return err
+			}
+			if prev != nil {
+				out = prev
+				return finish(repo, out, done)
+			}
+			key = k
+		}
+		var all []rules.Rule
+		var env *ruleEnv
+		if snap != nil {
+			all, env = snap.rules, snap.env(repo)
+		} else {
+			var err error
+			if all, err = repo.FindRules(); err != nil {
+				return err
+			}
+			env = newRuleEnv(repo, all)
+		}
+		dec, t, err := evaluate(env, all, in, mode)
+		if err != nil {
+			return err
+		}
+		trace = t
+		s.score(dec, trace)
+		audit := repository.AuditLog{
+			TransactionID: in.ID,
+			CustomerID:    in.CustomerID,
+			Decision:      *dec,
+			Transaction:   in,
+			Trace:         trace,
+		}
+		if err := repo.CreateAudit(&audit); err != nil {
+			return err
+		}
+		if dec.Status != rules.StatusReject {
+			// Not rejected: remember the transaction for future velocity
+			// checks. It is recorded at the audit's time, so history read
+			// before that time, as a backtest does, leaves it out.
+			rec := &repository.TransactionRecord{
+				TransactionID: in.ID,
+				CustomerID:    in.CustomerID,
+				FromAcc:       in.FromAcc,
+				ToAcc:         in.ToAcc,
+				Amount:        in.Amount,
+				Currency:      in.Currency,
+			}
+			rec.CreatedAt = audit.CreatedAt
+			if err := repo.CreateTransaction(rec); err != nil {
+				return err
+			}
+		}
+		if key != nil {
+			key.AuditID = audit.ID
+			if err := repo.SaveIdempotencyKey(key); err != nil {
+				return err
+			}
+		}
+		out = &audit.Decision
+		out.Results = summarize(trace)
+		if out.Status == rules.StatusManualReview {
+			c, err := openCase(repo, &audit, s.callbackURL(in))
+			if err != nil {
+				return err
+			}
+			out.CaseID = c.ID
+		}
+		return finish(repo, out, done)
+	})
 	if err != nil {
 		return nil, err
 	}
+	recordShadow(out, trace)
+	return out, nil
+}
 
-	for _, r := range amtRules {
-		if r.Threshold == nil {
-			// skip malformed rule
-			continue
+// finish calls done, if set, with the decision of a validation.
+func finish(repo repository.Repository, dec *rules.Decision, done func(repository.Repository, *rules.Decision) error) error {
+	if done == nil {
+		return nil
+	}
+	return done(repo, dec)
+}
+
+// evaluate runs the rules against in and returns the decision along with the
+// trace of every rule that was considered. Rule decisions are combined by
+// precedence: reject beats manual review, which beats approve. In
+// ModeFirstFailure evaluation stops at the first reject. all holds every
+// stored rule; env serves the data the rules look up.
+func evaluate(env *ruleEnv, all []rules.Rule, in dto.Transaction, mode EvaluationMode) (*rules.Decision, []rules.RuleResult, error) {
+	var trace []rules.RuleResult
+	outcome := rules.Approve()
+
+	// settle folds a rule decision into the outcome and reports whether
+	// evaluation should stop.
+	settle := func(dec rules.Decision) bool {
+		if dec.Status.Outranks(outcome.Status) {
+			outcome = dec
 		}
-		// create concrete rule and validate
-		ar := rules.AmountThresholdRule{
-			RuleBase:  r.RuleBase,
-			Threshold: *r.Threshold,
+		return mode == ModeFirstFailure && outcome.Status == rules.StatusReject
+	}
+
+	children := compositeChildren(all, in)
+	var live, shadow []rules.Rule
+	for _, r := range all {
+		switch {
+		case children[r.ID] || !r.ActiveAt(in.Timestamp):
+		case r.Shadow():
+			shadow = append(shadow, r)
+		default:
+			live = append(live, r)
 		}
-		dec := ar.Validate(in)
-		if !dec.Approved {
-			return &dec, nil
+	}
+	apply, overridden := rules.Applicable(live, in)
+	for _, o := range overridden {
+		trace = append(trace, rules.SkippedRuleResult(o.Rule.RuleBase, "overridden by a "+string(o.By)+" scoped rule"))
+	}
+
+	run := func(r *rules.Rule) (bool, error) {
+		dec, res, err := rules.Evaluate(r, in, env)
+		if err != nil {
+			return false, err
 		}
+		trace = append(trace, res)
+		return settle(dec), nil
 	}
 
-	// 2) Evaluate sanctions / blacklist rules: check if either account is sanctioned
-	// We rely on repository-level helper to check sanctions table quickly.
-	fromSanctioned, err := s.Repo.IsAccountSanctioned(in.FromAcc)
-	if err != nil {
-		return nil, err
+	// 1) Evaluate the stored rules that apply to the transaction, each built
+	// by the factory for its type, then always check both accounts against
+	// the sanctions lists
+	for _, r := range append(apply, &sanctionsRule) {
+		stop, err := run(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		if stop {
+			break
+		}
 	}
-	if fromSanctioned {
-		d := rules.Decision{Approved: false, Reason: "From account is sanctioned"}
-		return &d, nil
+
+	// 2) Evaluate shadow rules. They are traced but leave the outcome alone,
+	// and they neither override nor are overridden by live rules.
+	for i := range shadow {
+		r := &shadow[i]
+		if !r.AppliesTo(in) {
+			continue
+		}
+		_, res, err := rules.Evaluate(r, in, env)
+		if err != nil {
+			return nil, nil, err
+		}
+		trace = append(trace, res)
 	}
+	return &outcome, trace, nil
+}
 
-	toSanctioned, err := s.Repo.IsAccountSanctioned(in.ToAcc)
-	if err != nil {
-		return nil, err
+// compositeChildren returns the rules that run only as part of a composite:
+// the children, at any depth, of the live composites in effect for in. Rules
+// used by shadow, expired or out of scope composites still run on their own.
+func compositeChildren(all []rules.Rule, in dto.Transaction) map[uint]bool {
+	byID := make(map[uint]*rules.Rule, len(all))
+	for i := range all {
+		byID[all[i].ID] = &all[i]
 	}
-	if toSanctioned {
-		d := rules.Decision{Approved: false, Reason: "To account is sanctioned"}
-		return &d, nil
+	children := make(map[uint]bool)
+	var hide func(r *rules.Rule)
+	hide = func(r *rules.Rule) {
+		for _, id := range r.Children {
+			if children[id] {
+				continue
+			}
+			children[id] = true
+			if c := byID[id]; c != nil && c.Type == rules.RuleTypeComposite {
+				hide(c)
+			}
+		}
+	}
+	for i := range all {
+		r := &all[i]
+		if r.Type == rules.RuleTypeComposite && !r.Shadow() && r.ActiveAt(in.Timestamp) && r.AppliesTo(in) {
+			hide(r)
+		}
 	}
+	return children
+}
+
+// sanctionsRule is the built-in sanctions check run for every transaction.
+var sanctionsRule = rules.Rule{RuleBase: rules.RuleBase{Name: "sanctions", Type: rules.RuleTypeSanctionsList}}
 
-	// All checks passed
-	ok := rules.Decision{Approved: true, Reason: "OK"}
-	return &ok, nil
+// score sets the decision's risk score from the rule contributions in trace.
+// A score in a stricter band than the rules' combined status escalates the
+// decision to that band.
+func (s *ComplianceService) score(dec *rules.Decision, trace []rules.RuleResult) {
+	score := math.Round(rules.RiskScore(trace)*100) / 100
+	dec.RiskScore = score
+	band := s.Bands.Status(score)
+	if !band.Outranks(dec.Status) {
+		return
+	}
+	dec.Approved = false
+	dec.Status = band
+	dec.Reason = fmt.Sprintf("Risk score %.2f is in the %s band", score, band)
+}
 
+// summarize returns the trace without rule inputs or shadow results, for the
+// validation response. The full trace is kept in the audit log.
+func summarize(trace []rules.RuleResult) []rules.RuleResult {
+	out := make([]rules.RuleResult, 0, len(trace))
+	for _, r := range trace {
+		if r.Shadow {
+			continue
+		}
+		r.Inputs = nil
+		out = append(out, r)
+	}
+	return out
 }
@@ -0,0 +1,453 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/warleon/ms4-compliance-service/internal/dto"
+	"github.com/warleon/ms4-compliance-service/internal/money"
+	"github.com/warleon/ms4-compliance-service/internal/repository"
+	"github.com/warleon/ms4-compliance-service/internal/repository/rules"
+)
+
+// testEnv returns a ruleEnv that answers sanctions lookups from an empty list
+// and never touches a repository.
+func testEnv(all []rules.Rule) *ruleEnv {
+	e := newRuleEnv(nil, all)
+	e.accounts = map[string]struct{}{}
+	e.partiesLoaded = true
+	return e
+}
+
+func amountRule(id uint, threshold string, status rules.RuleStatus) rules.Rule {
+	t := money.MustParse(threshold)
+	r := rules.Rule{RuleExtras: rules.RuleExtras{Threshold: &t}}
+	r.ID, r.Name, r.Type, r.Status = id, "amount", rules.RuleTypeAmountThreshold, status
+	return r
+}
+
+func TestShadowRuleLeavesDecisionAlone(t *testing.T) {
+	s := NewComplianceService(nil)
+	tx := dto.Transaction{FromAcc: "A", ToAcc: "B", Amount: money.MustParse("100")}
+
+	for _, tc := range []struct {
+		name  string
+		rules []rules.Rule
+	}{
+		{"shadow only", []rules.Rule{amountRule(1, "10", rules.RuleShadow)}},
+		{"shadow and passing live rule", []rules.Rule{
+			amountRule(1, "1000", rules.RuleActive),
+			amountRule(2, "10", rules.RuleShadow),
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dec, trace, err := evaluate(testEnv(tc.rules), tc.rules, tx, ModeAll)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s.score(dec, trace)
+			if dec.Status != rules.StatusApprove || dec.RiskScore != 0 {
+				t.Fatalf("decision = %s with score %v, want approve with score 0", dec.Status, dec.RiskScore)
+			}
+
+			var shadow *rules.RuleResult
+			for i := range trace {
+				if trace[i].RuleID == tc.rules[len(tc.rules)-1].ID {
+					shadow = &trace[i]
+				}
+			}
+			if shadow == nil || !shadow.Shadow || shadow.Status != rules.StatusReject {
+				t.Fatalf("shadow result = %+v, want a shadow reject", shadow)
+			}
+			for _, r := range summarize(trace) {
+				if r.Shadow {
+					t.Fatalf("summarize kept shadow result %+v", r)
+				}
+			}
+		})
+	}
+}
+
+func TestLiveRuleRejects(t *testing.T) {
+	s := NewComplianceService(nil)
+	all := []rules.Rule{amountRule(1, "10", rules.RuleActive)}
+	tx := dto.Transaction{FromAcc: "A", ToAcc: "B", Amount: money.MustParse("100")}
+
+	dec, trace, err := evaluate(testEnv(all), all, tx, ModeAll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.score(dec, trace)
+	if dec.Status != rules.StatusReject || dec.RiskScore != rules.DefaultRejectWeight {
+		t.Fatalf("decision = %s with score %v, want reject with score %d", dec.Status, dec.RiskScore, rules.DefaultRejectWeight)
+	}
+}
+
+// reviewRule sends transactions over 10 to review. It is an expression rule,
+// so its reason differs from an amount rule's.
+func reviewRule(id uint) rules.Rule {
+	src := "amount > 10"
+	r := rules.Rule{RuleExtras: rules.RuleExtras{Expression: &src}}
+	r.ID, r.Name, r.Type, r.Outcome = id, "expression", rules.RuleTypeExpression, rules.StatusManualReview
+	return r
+}
+
+func TestDecisionTakesStrictestStatus(t *testing.T) {
+	const (
+		reviewReason = "Transaction matches rule expression"
+		rejectReason = "Transaction exceeds threshold"
+	)
+	tx := dto.Transaction{FromAcc: "A", ToAcc: "B", Amount: money.MustParse("100")}
+	for _, tc := range []struct {
+		name   string
+		rules  []rules.Rule
+		want   rules.DecisionStatus
+		reason string
+	}{
+		{"all pass", []rules.Rule{amountRule(1, "1000", rules.RuleActive)}, rules.StatusApprove, "OK"},
+		{"review", []rules.Rule{amountRule(1, "1000", rules.RuleActive), reviewRule(2)}, rules.StatusManualReview, reviewReason},
+		{"reject after review", []rules.Rule{reviewRule(1), amountRule(2, "10", rules.RuleActive)}, rules.StatusReject, rejectReason},
+		{"review after reject", []rules.Rule{amountRule(1, "10", rules.RuleActive), reviewRule(2)}, rules.StatusReject, rejectReason},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dec, _, err := evaluate(testEnv(tc.rules), tc.rules, tx, ModeAll)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if dec.Status != tc.want || dec.Approved != (tc.want == rules.StatusApprove) || dec.Reason != tc.reason {
+				t.Fatalf("decision = %s (approved %v, %q), want %s (%q)", dec.Status, dec.Approved, dec.Reason, tc.want, tc.reason)
+			}
+		})
+	}
+}
+
+func TestEvaluationModes(t *testing.T) {
+	tx := dto.Transaction{FromAcc: "A", ToAcc: "B", Amount: money.MustParse("100")}
+	reject := func(id uint) rules.Rule { return amountRule(id, "10", rules.RuleActive) }
+	all := []rules.Rule{reviewRule(1), reject(2), reject(3), amountRule(4, "10", rules.RuleShadow)}
+	for _, tc := range []struct {
+		mode EvaluationMode
+		want []uint // rule IDs in the trace; 0 is the sanctions check
+	}{
+		{ModeAll, []uint{1, 2, 3, 0, 4}},
+		// review does not stop evaluation, the first reject does; shadow
+		// rules still run
+		{ModeFirstFailure, []uint{1, 2, 4}},
+	} {
+		t.Run(string(tc.mode), func(t *testing.T) {
+			dec, trace, err := evaluate(testEnv(all), all, tx, tc.mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []uint
+			for _, r := range trace {
+				got = append(got, r.RuleID)
+			}
+			if dec.Status != rules.StatusReject || !slices.Equal(got, tc.want) {
+				t.Errorf("decision %s with trace %v, want reject with %v", dec.Status, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestValidateTransactionMode(t *testing.T) {
+	repo := &validationRepo{rules: []rules.Rule{amountRule(1, "10", rules.RuleActive), amountRule(2, "10", rules.RuleActive)}}
+	s := NewComplianceService(repo)
+	s.DefaultMode = ModeFirstFailure
+	in := dto.Transaction{FromAcc: "A", ToAcc: "B", Amount: money.MustParse("100")}
+	if _, err := s.ValidateTransaction(context.Background(), in, "fastest"); !errors.Is(err, ErrInvalidMode) {
+		t.Errorf("unknown mode: err = %v, want ErrInvalidMode", err)
+	}
+	for _, tc := range []struct {
+		mode    EvaluationMode
+		results int
+	}{
+		{"", 1}, // the service default
+		{ModeAll, 3},
+		{ModeFirstFailure, 1},
+	} {
+		dec, err := s.ValidateTransaction(context.Background(), in, tc.mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(dec.Results) != tc.results {
+			t.Errorf("mode %q: %d results, want %d", tc.mode, len(dec.Results), tc.results)
+		}
+	}
+}
+
+func compositeRule(id uint, op rules.CompositeOp, status rules.RuleStatus, children ...uint) rules.Rule {
+	r := rules.Rule{RuleExtras: rules.RuleExtras{Operator: &op, Children: children}}
+	r.ID, r.Name, r.Type, r.Status = id, "composite", rules.RuleTypeComposite, status
+	return r
+}
+
+func TestCompositeChildrenRunAlone(t *testing.T) {
+	s := NewComplianceService(nil)
+	tx := dto.Transaction{FromAcc: "A", ToAcc: "B", Amount: money.MustParse("100"), Timestamp: time.Now()}
+	expired := compositeRule(2, rules.CompositeNot, rules.RuleActive, 1)
+	past := tx.Timestamp.Add(-time.Hour)
+	expired.EffectiveTo = &past
+
+	for _, tc := range []struct {
+		name      string
+		composite rules.Rule
+		want      rules.DecisionStatus
+	}{
+		// the child rejects on its own
+		{"shadow composite", compositeRule(2, rules.CompositeNot, rules.RuleShadow, 1), rules.StatusReject},
+		{"expired composite", expired, rules.StatusReject},
+		// the child only runs inside the composite, which passes
+		{"live composite", compositeRule(2, rules.CompositeNot, rules.RuleActive, 1), rules.StatusApprove},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			all := []rules.Rule{amountRule(1, "10", rules.RuleActive), tc.composite}
+			dec, trace, err := evaluate(testEnv(all), all, tx, ModeAll)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s.score(dec, trace)
+			if dec.Status != tc.want {
+				t.Fatalf("decision = %s, want %s", dec.Status, tc.want)
+			}
+		})
+	}
+}
+
+func TestScore(t *testing.T) {
+	review := func(id uint, w *float64) rules.Rule {
+		r := amountRule(id, "10", rules.RuleActive)
+		r.Outcome, r.Weight = rules.StatusManualReview, w
+		return r
+	}
+	zero := 0.0
+	tx := dto.Transaction{FromAcc: "A", ToAcc: "B", Amount: money.MustParse("100")}
+
+	for _, tc := range []struct {
+		name      string
+		bands     rules.RiskBands
+		rules     []rules.Rule
+		want      rules.DecisionStatus
+		wantScore float64
+	}{
+		{"review rules stay in review by default", rules.DefaultRiskBands,
+			[]rules.Rule{review(1, nil), review(2, nil)}, rules.StatusManualReview, 100},
+		{"review rules reject with a reject band", rules.RiskBands{Review: 40, Reject: 80},
+			[]rules.Rule{review(1, nil), review(2, nil)}, rules.StatusReject, 100},
+		{"one review rule below the reject band", rules.RiskBands{Review: 40, Reject: 80},
+			[]rules.Rule{review(1, nil)}, rules.StatusManualReview, 50},
+		{"a weightless reject still rejects", rules.RiskBands{Review: 40, Reject: 80},
+			[]rules.Rule{func() rules.Rule { r := amountRule(1, "10", rules.RuleActive); r.Weight = &zero; return r }()},
+			rules.StatusReject, 0},
+		{"passing rules approve", rules.DefaultRiskBands,
+			[]rules.Rule{amountRule(1, "1000", rules.RuleActive)}, rules.StatusApprove, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := NewComplianceService(nil)
+			s.Bands = tc.bands
+			dec, trace, err := evaluate(testEnv(tc.rules), tc.rules, tx, ModeAll)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s.score(dec, trace)
+			if dec.Status != tc.want || dec.RiskScore != tc.wantScore || dec.Approved != (tc.want == rules.StatusApprove) {
+				t.Fatalf("decision = %s (approved %v) with score %v, want %s with score %v",
+					dec.Status, dec.Approved, dec.RiskScore, tc.want, tc.wantScore)
+			}
+		})
+	}
+}
+
+func TestOverriddenRulesAreTraced(t *testing.T) {
+	s := NewComplianceService(nil)
+	global := amountRule(1, "10", rules.RuleActive)
+	customer := amountRule(2, "1000", rules.RuleActive)
+	customer.Scope, customer.ScopeValue = rules.ScopeCustomer, "C1"
+	all := []rules.Rule{global, customer}
+
+	// the customer's higher limit replaces the global one
+	tx := dto.Transaction{FromAcc: "A", ToAcc: "B", CustomerID: "C1", Amount: money.MustParse("100")}
+	dec, trace, err := evaluate(testEnv(all), all, tx, ModeAll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.score(dec, trace)
+	if dec.Status != rules.StatusApprove {
+		t.Fatalf("decision = %s, want approve", dec.Status)
+	}
+	if r := trace[0]; r.RuleID != 1 || !r.Skipped || r.Reason != "overridden by a customer scoped rule" {
+		t.Fatalf("first trace entry = %+v, want rule 1 overridden", r)
+	}
+	if r := trace[1]; r.RuleID != 2 || r.Skipped || r.Scope != "customer:C1" {
+		t.Fatalf("second trace entry = %+v, want rule 2 evaluated", r)
+	}
+
+	// other customers keep the global limit
+	tx.CustomerID = "C2"
+	dec, trace, err = evaluate(testEnv(all), all, tx, ModeAll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dec.Status != rules.StatusReject || trace[0].RuleID != 1 || trace[0].Skipped {
+		t.Fatalf("decision = %s with trace %+v, want rule 1 to reject", dec.Status, trace)
+	}
+}
+
+// validationRepo stores what a validation writes: audits, transaction
+// records, cases and idempotency keys. CreateAudit fails with auditErr when
+// it is set.
+type validationRepo struct {
+	repository.Repository
+	rules    []rules.Rule
+	audits   []repository.AuditLog
+	records  []repository.TransactionRecord
+	cases    []repository.Case
+	keys     map[string]*repository.IdempotencyKey
+	auditErr error
+}
+
+// WithTx rolls the audits, records, cases and keys back when fn fails.
+func (r *validationRepo) WithTx(fn func(repo repository.Repository) error) error {
+	audits, records, cases := len(r.audits), len(r.records), len(r.cases)
+	keys := make(map[string]*repository.IdempotencyKey, len(r.keys))
+	for id, k := range r.keys {
+		cp := *k
+		keys[id] = &cp
+	}
+	if err := fn(r); err != nil {
+		r.audits, r.records, r.cases, r.keys = r.audits[:audits], r.records[:records], r.cases[:cases], keys
+		return err
+	}
+	return nil
+}
+
+func (r *validationRepo) FindRules() ([]rules.Rule, error)                 { return r.rules, nil }
+func (r *validationRepo) FindSanctionedParties() ([]rules.Sanction, error) { return nil, nil }
+func (r *validationRepo) IsAccountSanctioned(accID string) (bool, error)   { return false, nil }
+
+func (r *validationRepo) CreateAudit(a *repository.AuditLog) error {
+	if r.auditErr != nil {
+		return r.auditErr
+	}
+	a.ID = uint(len(r.audits) + 1)
+	a.CreatedAt = time.Now()
+	r.audits = append(r.audits, *a)
+	return nil
+}
+
+func (r *validationRepo) ReadAudit(id uint) (*repository.AuditLog, error) {
+	a := r.audits[id-1]
+	return &a, nil
+}
+
+func (r *validationRepo) CreateTransaction(rec *repository.TransactionRecord) error {
+	r.records = append(r.records, *rec)
+	return nil
+}
+
+func (r *validationRepo) CreateCase(c *repository.Case) error {
+	c.ID = uint(len(r.cases) + 1)
+	r.cases = append(r.cases, *c)
+	return nil
+}
+
+func (r *validationRepo) CreateAuditEvent(e *repository.AuditEvent) error { return nil }
+
+func (r *validationRepo) FindCases(f repository.CaseFilter) ([]repository.Case, error) {
+	var out []repository.Case
+	for _, c := range r.cases {
+		if c.AuditID == f.AuditID {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+func (r *validationRepo) ClaimIdempotencyKey(k *repository.IdempotencyKey) (*repository.IdempotencyKey, error) {
+	if prev, ok := r.keys[k.TransactionID]; ok {
+		cp := *prev
+		return &cp, nil
+	}
+	if r.keys == nil {
+		r.keys = make(map[string]*repository.IdempotencyKey)
+	}
+	k.CreatedAt = time.Now()
+	cp := *k
+	r.keys[k.TransactionID] = &cp
+	return nil, nil
+}
+
+func (r *validationRepo) SaveIdempotencyKey(k *repository.IdempotencyKey) error {
+	cp := *k
+	r.keys[k.TransactionID] = &cp
+	return nil
+}
+
+func TestValidateTransactionStoresAudit(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		amount string
+		want   rules.DecisionStatus
+		record bool // whether the transaction is kept as history
+	}{
+		{"approved", "5", rules.StatusApprove, true},
+		{"rejected", "100", rules.StatusReject, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &validationRepo{rules: []rules.Rule{amountRule(1, "10", rules.RuleActive)}}
+			s := NewComplianceService(repo)
+			in := dto.Transaction{ID: "tx-1", CustomerID: "C1", FromAcc: "A", ToAcc: "B", Amount: money.MustParse(tc.amount), Currency: "USD"}
+			dec, err := s.ValidateTransaction(context.Background(), in, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if dec.Status != tc.want || len(repo.audits) != 1 {
+				t.Fatalf("decision %s with %d audits, want %s with 1", dec.Status, len(repo.audits), tc.want)
+			}
+			a := repo.audits[0]
+			if a.TransactionID != "tx-1" || a.CustomerID != "C1" || a.Decision.Status != tc.want || a.Transaction.Amount != in.Amount {
+				t.Errorf("audit = %+v", a)
+			}
+			// the trace holds every rule with its inputs; the response leaves
+			// the inputs out
+			if len(a.Trace) != 2 || a.Trace[0].RuleID != 1 || a.Trace[1].Type != rules.RuleTypeSanctionsList {
+				t.Fatalf("trace = %+v, want the amount rule then the sanctions check", a.Trace)
+			}
+			if a.Trace[0].Status != tc.want || a.Trace[0].Inputs["threshold"] == nil {
+				t.Errorf("amount rule result = %+v", a.Trace[0])
+			}
+			if len(dec.Results) != 2 || dec.Results[0].Inputs != nil {
+				t.Errorf("decision results = %+v, want the trace without inputs", dec.Results)
+			}
+			if got := len(repo.records) == 1; got != tc.record {
+				t.Errorf("transaction recorded = %v, want %v", got, tc.record)
+			}
+			if tc.record && !repo.records[0].CreatedAt.Equal(a.CreatedAt) {
+				t.Errorf("record created at %s, audit at %s", repo.records[0].CreatedAt, a.CreatedAt)
+			}
+		})
+	}
+}
+
+func TestValidateTransactionFailsWithoutAudit(t *testing.T) {
+	repo := &validationRepo{auditErr: errors.New("disk full")}
+	s := NewComplianceService(repo)
+	s.IdempotencyRetention = time.Hour
+	in := dto.Transaction{ID: "tx-1", FromAcc: "A", ToAcc: "B", Amount: money.MustParse("5")}
+	if dec, err := s.ValidateTransaction(context.Background(), in, ""); !errors.Is(err, repo.auditErr) || dec != nil {
+		t.Fatalf("ValidateTransaction = %v, %v; want the audit error", dec, err)
+	}
+	if len(repo.records) != 0 || len(repo.keys) != 0 {
+		t.Errorf("%d records, %d idempotency keys stored without an audit", len(repo.records), len(repo.keys))
+	}
+
+	// once the audit log is writable the same transaction is validated anew
+	repo.auditErr = nil
+	dec, err := s.ValidateTransaction(context.Background(), in, "")
+	if err != nil || dec.Replayed || len(repo.audits) != 1 {
+		t.Fatalf("retry = %+v, %v with %d audits; want a fresh decision", dec, err, len(repo.audits))
+	}
+}
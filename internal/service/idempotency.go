@@ -0,0 +1,85 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/warleon/ms4-compliance-service/internal/dto"
+	"github.com/warleon/ms4-compliance-service/internal/repository"
+	"github.com/warleon/ms4-compliance-service/internal/repository/rules"
+)
+
+// ErrIdempotencyConflict is returned when a transaction ID that was already
+// validated comes back with a different payload.
+var ErrIdempotencyConflict = errors.New("transaction id was already validated with a different payload")
+
+// payloadHash fingerprints a validation request so a retry can be told apart
+// from a different transaction reusing the ID.
+func payloadHash(in dto.Transaction) (string, error) {
+	b, err := json.Marshal(in)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// claimTransaction reserves in.ID for a validation. It returns either the key
+// to complete with the audit ID once the decision is stored, or the stored
+// decision when the ID was already validated with the same payload. Keys older
+// than the retention period are reused as if they were new.
+func (s *ComplianceService) claimTransaction(repo repository.Repository, in dto.Transaction, hash string) (*repository.IdempotencyKey, *rules.Decision, error) {
+	key := &repository.IdempotencyKey{TransactionID: in.ID, PayloadHash: hash}
+	prev, err := repo.ClaimIdempotencyKey(key)
+	if err != nil || prev == nil {
+		return key, nil, err
+	}
+	if prev.AuditID == 0 || time.Since(prev.CreatedAt) >= s.IdempotencyRetention {
+		prev.PayloadHash = hash
+		prev.AuditID = 0
+		prev.CreatedAt = time.Now()
+		return prev, nil, nil
+	}
+	if prev.PayloadHash != hash {
+		return nil, nil, ErrIdempotencyConflict
+	}
+	audit, err := repo.ReadAudit(prev.AuditID)
+	if err != nil {
+		return nil, nil, err
+	}
+	dec := audit.Decision
+	dec.Results = summarize(audit.Trace)
+	dec.Replayed = true
+	if dec.Status == rules.StatusManualReview {
+		cs, err := repo.FindCases(repository.CaseFilter{AuditID: audit.ID, Size: 1})
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(cs) > 0 {
+			dec.CaseID = cs[0].ID
+		}
+	}
+	return nil, &dec, nil
+}
+
+// PurgeIdempotencyKeys deletes keys past the retention period every interval.
+// It never returns.
+func (s *ComplianceService) PurgeIdempotencyKeys(interval time.Duration) {
+	for range time.Tick(interval) {
+		if s.IdempotencyRetention <= 0 {
+			continue
+		}
+		n, err := s.Repo.DeleteIdempotencyKeys(time.Now().Add(-s.IdempotencyRetention))
+		if err != nil {
+			logrus.WithError(err).Warn("idempotency key purge failed")
+			continue
+		}
+		if n > 0 {
+			logrus.WithField("deleted", n).Info("purged expired idempotency keys")
+		}
+	}
+}
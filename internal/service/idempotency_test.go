@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/warleon/ms4-compliance-service/internal/dto"
+	"github.com/warleon/ms4-compliance-service/internal/money"
+	"github.com/warleon/ms4-compliance-service/internal/repository/rules"
+)
+
+func TestRepeatedTransactionIsReplayed(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		rule   rules.Rule
+		status rules.DecisionStatus
+	}{
+		{"approved", amountRule(1, "1000", rules.RuleActive), rules.StatusApprove},
+		{"sent to review", reviewRule(1), rules.StatusManualReview},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &validationRepo{rules: []rules.Rule{tc.rule}}
+			s := NewComplianceService(repo)
+			s.IdempotencyRetention = time.Hour
+			// no timestamp: the payload is compared as sent, before it is
+			// filled in
+			in := dto.Transaction{ID: "tx-1", FromAcc: "A", ToAcc: "B", Amount: money.MustParse("100")}
+
+			first, err := s.ValidateTransaction(context.Background(), in, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			again, err := s.ValidateTransaction(context.Background(), in, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if first.Replayed || !again.Replayed {
+				t.Errorf("replayed = %v then %v, want false then true", first.Replayed, again.Replayed)
+			}
+			if again.Status != tc.status || again.Reason != first.Reason || again.CaseID != first.CaseID || len(again.Results) != len(first.Results) {
+				t.Errorf("replay = %+v, want the first decision %+v", again, first)
+			}
+			if tc.status == rules.StatusManualReview && again.CaseID == 0 {
+				t.Error("replayed review decision has no case")
+			}
+			if len(repo.audits) != 1 || len(repo.records) > 1 || len(repo.cases) > 1 {
+				t.Errorf("%d audits, %d records, %d cases; the replay stored something", len(repo.audits), len(repo.records), len(repo.cases))
+			}
+		})
+	}
+}
+
+func TestIdempotencyKeys(t *testing.T) {
+	in := dto.Transaction{ID: "tx-1", FromAcc: "A", ToAcc: "B", Amount: money.MustParse("100")}
+	changed := in
+	changed.Amount = money.MustParse("101")
+	noID := in
+	noID.ID = ""
+
+	for _, tc := range []struct {
+		name      string
+		retention time.Duration
+		age       time.Duration // age of the first key when the second request comes
+		second    dto.Transaction
+		err       error
+		audits    int
+	}{
+		{"different payload", time.Hour, 0, changed, ErrIdempotencyConflict, 1},
+		{"key past retention", time.Hour, 2 * time.Hour, changed, nil, 2},
+		{"no transaction ID", time.Hour, 0, noID, nil, 2},
+		{"idempotency off", 0, 0, in, nil, 2},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &validationRepo{}
+			s := NewComplianceService(repo)
+			s.IdempotencyRetention = tc.retention
+			if _, err := s.ValidateTransaction(context.Background(), in, ""); err != nil {
+				t.Fatal(err)
+			}
+			for _, k := range repo.keys {
+				k.CreatedAt = k.CreatedAt.Add(-tc.age)
+			}
+			dec, err := s.ValidateTransaction(context.Background(), tc.second, "")
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("err = %v, want %v", err, tc.err)
+			}
+			if err == nil && dec.Replayed {
+				t.Error("decision was replayed")
+			}
+			if len(repo.audits) != tc.audits {
+				t.Errorf("%d audits, want %d", len(repo.audits), tc.audits)
+			}
+			if k := repo.keys[in.ID]; tc.age > 0 && (k.AuditID != 2 || time.Since(k.CreatedAt) > time.Minute) {
+				t.Errorf("reused key = %+v, want it pointing at the new audit", k)
+			}
+		})
+	}
+}
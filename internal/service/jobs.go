@@ -0,0 +1,388 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/warleon/ms4-compliance-service/internal/dto"
+	"github.com/warleon/ms4-compliance-service/internal/notify"
+	"github.com/warleon/ms4-compliance-service/internal/repository"
+	"github.com/warleon/ms4-compliance-service/internal/repository/rules"
+	"gorm.io/gorm"
+)
+
+// DefaultJobMaxItems is the default cap on the items of one job.
+const DefaultJobMaxItems = 100000
+
+// jobChunkSize is how many items a job screens between progress updates.
+const jobChunkSize = 100
+
+var (
+	ErrInvalidJob  = errors.New("invalid job")
+	ErrJobNotFound = errors.New("job not found")
+)
+
+// AccountInput is one account of an accounts job. Name, when given, is
+// screened against the names of sanctioned parties.
+type AccountInput struct {
+	AccID string `json:"accId"`
+	Name  string `json:"name,omitempty"`
+}
+
+// AccountResult is the screening result for one account of an accounts job.
+// Status is reject for a sanctioned account, otherwise the outcome of the name
+// screening.
+type AccountResult struct {
+	AccID      string               `json:"accId"`
+	Name       string               `json:"name,omitempty"`
+	Sanctioned bool                 `json:"sanctioned"`
+	Status     rules.DecisionStatus `json:"status"`
+	Matches    []rules.NameMatch    `json:"matches,omitempty"`
+}
+
+// JobUpload describes a file submitted as a job.
+type JobUpload struct {
+	Kind repository.JobKind
+	// Format of an accounts file: csv or jsonl. Transactions are read as a
+	// JSON array or NDJSON.
+	Format      string
+	Mode        EvaluationMode
+	CallbackURL string
+}
+
+// SubmitJob stores the items read from r as a queued job and wakes the job
+// runner. The whole file is checked before anything is stored.
+func (s *ComplianceService) SubmitJob(ctx context.Context, up JobUpload, r io.Reader) (*repository.Job, error) {
+	if !up.Kind.Valid() {
+		return nil, fmt.Errorf("%w: kind must be transactions or accounts", ErrInvalidJob)
+	}
+	if up.CallbackURL != "" && !s.CallbackHosts.Allows(up.CallbackURL) {
+		return nil, fmt.Errorf("%w: callback URL must be an http(s) URL on an allowed host", ErrInvalidJob)
+	}
+	var inputs []any
+	switch up.Kind {
+	case repository.JobTransactions:
+		if up.Mode == "" {
+			up.Mode = s.DefaultMode
+		}
+		if !up.Mode.Valid() {
+			return nil, ErrInvalidMode
+		}
+		txs, err := ReadTransactions(r, s.JobMaxItems)
+		if err != nil {
+			return nil, err
+		}
+		for _, tx := range txs {
+			inputs = append(inputs, tx)
+		}
+	case repository.JobAccounts:
+		up.Mode = ""
+		accounts, err := readAccounts(up.Format, r, s.JobMaxItems)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range accounts {
+			inputs = append(inputs, a)
+		}
+	}
+
+	job := &repository.Job{
+		Kind:        up.Kind,
+		Status:      repository.JobQueued,
+		Mode:        string(up.Mode),
+		CallbackURL: up.CallbackURL,
+		Total:       len(inputs),
+	}
+	err := s.Repo.WithTx(func(repo repository.Repository) error {
+		if err := repo.CreateJob(job); err != nil {
+			return err
+		}
+		items := make([]repository.JobItem, len(inputs))
+		for i, in := range inputs {
+			b, err := json.Marshal(in)
+			if err != nil {
+				return err
+			}
+			items[i] = repository.JobItem{JobID: job.ID, Seq: i + 1, Input: b}
+		}
+		return repo.CreateJobItems(items)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.wakeJobs()
+	return job, nil
+}
+
+// readAccounts reads an accounts file. CSV takes the account from the first
+// column and an optional name from the second; a header row starting with
+// accId or acc_id is skipped. JSONL expects one AccountInput object per line.
+func readAccounts(format string, r io.Reader, max int) ([]AccountInput, error) {
+	var out []AccountInput
+	add := func(line int, a AccountInput) error {
+		a.AccID, a.Name = strings.TrimSpace(a.AccID), strings.TrimSpace(a.Name)
+		if !validAccID(a.AccID) {
+			return fmt.Errorf("%w: line %d: %v", ErrInvalidJob, line, ErrInvalidSanction)
+		}
+		if len(out) == max {
+			return fmt.Errorf("%w: the limit is %d", ErrBatchTooLarge, max)
+		}
+		out = append(out, a)
+		return nil
+	}
+
+	switch format {
+	case SanctionFormatCSV:
+		cr := csv.NewReader(r)
+		cr.FieldsPerRecord = -1
+		cr.TrimLeadingSpace = true
+		for first := true; ; first = false {
+			rec, err := cr.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%w: %w", ErrInvalidJob, err)
+			}
+			line, _ := cr.FieldPos(0)
+			id := strings.TrimSpace(rec[0])
+			if first && (strings.EqualFold(id, "accId") || strings.EqualFold(id, "acc_id")) {
+				continue
+			}
+			a := AccountInput{AccID: id}
+			if len(rec) > 1 {
+				a.Name = rec[1]
+			}
+			if err := add(line, a); err != nil {
+				return nil, err
+			}
+		}
+	case SanctionFormatJSONL:
+		sc := bufio.NewScanner(r)
+		sc.Buffer(make([]byte, 64*1024), 1024*1024)
+		for line := 1; sc.Scan(); line++ {
+			text := strings.TrimSpace(sc.Text())
+			if text == "" {
+				continue
+			}
+			var a AccountInput
+			if err := json.Unmarshal([]byte(text), &a); err != nil {
+				return nil, fmt.Errorf("%w: line %d: invalid JSON", ErrInvalidJob, line)
+			}
+			if err := add(line, a); err != nil {
+				return nil, err
+			}
+		}
+		if err := sc.Err(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: accounts format must be csv or jsonl", ErrInvalidJob)
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("%w: no accounts", ErrInvalidJob)
+	}
+	return out, nil
+}
+
+// GetJob returns a job with its progress.
+func (s *ComplianceService) GetJob(ctx context.Context, id uint) (*repository.Job, error) {
+	j, err := s.Repo.ReadJob(id)
+	if err == gorm.ErrRecordNotFound {
+		return nil, ErrJobNotFound
+	}
+	return j, err
+}
+
+// JobResults returns up to size results of a job after the item at afterSeq,
+// in file order. Results are available as soon as their item is screened.
+func (s *ComplianceService) JobResults(ctx context.Context, id uint, afterSeq, size int) ([]repository.JobItem, error) {
+	return s.Repo.ReadJobResults(id, afterSeq, size)
+}
+
+// wakeJobs tells the job runner that a job was submitted.
+func (s *ComplianceService) wakeJobs() {
+	select {
+	case s.jobWake <- struct{}{}:
+	default:
+	}
+}
+
+// jobLease is how long a process holds a job without saving progress before
+// another process may take it over.
+const jobLease = 2 * time.Minute
+
+// RunJobs works through the queued jobs, and any whose lease expired, one at
+// a time. It claims each job first, so replicas sharing the database never
+// work on the same job. It looks for work when a job is submitted and every
+// interval. It never returns.
+func (s *ComplianceService) RunJobs(interval time.Duration) {
+	tick := time.Tick(interval)
+	for {
+		for {
+			j, err := s.Repo.ClaimJob(s.jobOwner, jobLease)
+			if err != nil {
+				logrus.WithError(err).Warn("failed to claim a validation job")
+				break
+			}
+			if j == nil {
+				break
+			}
+			if err := s.runJob(j); err != nil {
+				logrus.WithError(err).WithField("job", j.ID).Warn("validation job interrupted, will retry")
+				break
+			}
+		}
+		select {
+		case <-s.jobWake:
+		case <-tick:
+		}
+	}
+}
+
+// runJob screens the pending items of j in chunks. Each item's result is
+// stored as it is screened, in the same database transaction as the audit of
+// a validated transaction, and only if no other process stored it first, so
+// an item is never screened twice. The lease is renewed, and the progress
+// counted from the stored results, before and after every chunk; once the
+// lease is lost the job is left to the process that took it over. On other
+// errors the job is left running and picks up where it stopped on the next
+// attempt.
+func (s *ComplianceService) runJob(j *repository.Job) error {
+	if j.Status == repository.JobQueued {
+		now := time.Now()
+		j.Status, j.StartedAt = repository.JobRunning, &now
+	}
+	for {
+		// renew the lease, and store a queued job as running, before screening
+		if err := s.saveJob(j); err != nil {
+			return err
+		}
+		items, err := s.Repo.PendingJobItems(j.ID, min(jobChunkSize, s.BatchMaxSize))
+		if err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			break
+		}
+		if err := s.screenItems(j, items); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	j.Status, j.FinishedAt = repository.JobCompleted, &now
+	if err := s.saveJob(j); err != nil {
+		return err
+	}
+	if j.CallbackURL != "" && s.CallbackHosts.Allows(j.CallbackURL) {
+		notify.Post(j.CallbackURL, j)
+	}
+	return nil
+}
+
+// saveJob stores j with its progress, provided this process still holds the
+// job, and extends its lease.
+func (s *ComplianceService) saveJob(j *repository.Job) error {
+	return s.Repo.WithTx(func(repo repository.Repository) error {
+		if err := repo.HoldJob(j, s.jobOwner, jobLease); err != nil {
+			return err
+		}
+		var err error
+		if j.Processed, j.Failed, err = repo.JobProgress(j.ID); err != nil {
+			return err
+		}
+		return repo.UpdateJob(j)
+	})
+}
+
+// screenItems screens items and stores their results. Transactions go
+// through batch validation, so each one is audited like a single validation,
+// and its result is stored along with its audit entry. Items another process
+// stored a result for in the meantime are left as they are.
+func (s *ComplianceService) screenItems(j *repository.Job, items []repository.JobItem) error {
+	switch j.Kind {
+	case repository.JobTransactions:
+		txs := make([]dto.Transaction, len(items))
+		for i, it := range items {
+			if err := json.Unmarshal(it.Input, &txs[i]); err != nil {
+				return err
+			}
+		}
+		taken := make([]bool, len(items))
+		res, err := s.validateBatch(context.Background(), txs, EvaluationMode(j.Mode), func(i int, repo repository.Repository, dec *rules.Decision) error {
+			err := finishItem(repo, &items[i], BatchResult{TransactionID: txs[i].ID, Decision: dec}, false)
+			taken[i] = errors.Is(err, repository.ErrJobItemDone)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		for i := range items {
+			if res[i].Error == "" || taken[i] {
+				continue
+			}
+			if err := finishItem(s.Repo, &items[i], res[i], true); err != nil && !errors.Is(err, repository.ErrJobItemDone) {
+				return err
+			}
+		}
+	case repository.JobAccounts:
+		snap, err := loadSnapshot(s.Repo)
+		if err != nil {
+			return err
+		}
+		for i, it := range items {
+			var a AccountInput
+			if err := json.Unmarshal(it.Input, &a); err != nil {
+				return err
+			}
+			if err := finishItem(s.Repo, &items[i], snap.screenAccount(a), false); err != nil && !errors.Is(err, repository.ErrJobItemDone) {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unknown job kind %q", j.Kind)
+	}
+	return nil
+}
+
+// finishItem stores result as the result of it.
+func finishItem(repo repository.Repository, it *repository.JobItem, result any, failed bool) error {
+	b, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	it.Result, it.Failed = b, failed
+	return repo.FinishJobItem(it)
+}
+
+// screenAccount checks an account against the sanctioned accounts and its
+// name, if any, against the sanctioned parties, as the built-in sanctions
+// check and a default name screening rule would.
+func (s *snapshot) screenAccount(a AccountInput) AccountResult {
+	res := AccountResult{AccID: a.AccID, Name: a.Name, Status: rules.StatusApprove}
+	if _, ok := s.accounts[a.AccID]; ok {
+		res.Sanctioned, res.Status = true, rules.StatusReject
+	}
+	if a.Name != "" {
+		nr := rules.NameScreeningRule{
+			MetadataKey: rules.DefaultNameMetadataKey,
+			Threshold:   rules.DefaultMatchThreshold,
+			Candidates:  s.parties,
+		}
+		dec := nr.Validate(dto.Transaction{Metadata: map[string]any{nr.MetadataKey: a.Name}})
+		res.Matches = dec.Matches
+		if dec.Status.Outranks(res.Status) {
+			res.Status = dec.Status
+		}
+	}
+	return res
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/warleon/ms4-compliance-service/internal/dto"
+	"github.com/warleon/ms4-compliance-service/internal/money"
+	"github.com/warleon/ms4-compliance-service/internal/repository"
+	"github.com/warleon/ms4-compliance-service/internal/repository/rules"
+)
+
+// jobRepo holds one job with its items, and the audits and transaction
+// records its validations leave behind. Leases expire against now.
+type jobRepo struct {
+	repository.Repository
+	now     time.Time
+	job     repository.Job
+	items   []repository.JobItem
+	audits  []repository.AuditLog
+	records []repository.TransactionRecord
+}
+
+func newJobRepo(t *testing.T, txs ...dto.Transaction) *jobRepo {
+	t.Helper()
+	r := &jobRepo{now: time.Now()}
+	r.job.ID, r.job.Kind, r.job.Status, r.job.Total = 1, repository.JobTransactions, repository.JobQueued, len(txs)
+	for i, tx := range txs {
+		b, err := json.Marshal(tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.items = append(r.items, repository.JobItem{ID: uint(i + 1), JobID: 1, Seq: i + 1, Input: b})
+	}
+	return r
+}
+
+// WithTx rolls the audits, records and items back when fn fails.
+func (r *jobRepo) WithTx(fn func(repo repository.Repository) error) error {
+	audits, records := len(r.audits), len(r.records)
+	items := append([]repository.JobItem(nil), r.items...)
+	if err := fn(r); err != nil {
+		r.audits, r.records, r.items = r.audits[:audits], r.records[:records], items
+		return err
+	}
+	return nil
+}
+
+func (r *jobRepo) FindRules() ([]rules.Rule, error)                 { return nil, nil }
+func (r *jobRepo) FindSanctionedParties() ([]rules.Sanction, error) { return nil, nil }
+func (r *jobRepo) ListSanctionedAccounts() ([]string, error)        { return nil, nil }
+
+func (r *jobRepo) CreateAudit(a *repository.AuditLog) error {
+	a.ID = uint(len(r.audits) + 1)
+	r.audits = append(r.audits, *a)
+	return nil
+}
+
+func (r *jobRepo) CreateTransaction(rec *repository.TransactionRecord) error {
+	r.records = append(r.records, *rec)
+	return nil
+}
+
+func (r *jobRepo) ClaimJob(owner string, lease time.Duration) (*repository.Job, error) {
+	j := &r.job
+	if j.Status == repository.JobCompleted || (j.Owner != owner && j.LeaseUntil != nil && !j.LeaseUntil.Before(r.now)) {
+		return nil, nil
+	}
+	until := r.now.Add(lease)
+	j.Owner, j.LeaseUntil = owner, &until
+	cp := *j
+	return &cp, nil
+}
+
+func (r *jobRepo) HoldJob(j *repository.Job, owner string, lease time.Duration) error {
+	if r.job.Owner != owner {
+		return repository.ErrJobLeaseLost
+	}
+	until := r.now.Add(lease)
+	r.job.LeaseUntil, j.Owner, j.LeaseUntil = &until, owner, &until
+	return nil
+}
+
+func (r *jobRepo) UpdateJob(j *repository.Job) error {
+	r.job = *j
+	return nil
+}
+
+func (r *jobRepo) PendingJobItems(jobID uint, size int) ([]repository.JobItem, error) {
+	var out []repository.JobItem
+	for _, it := range r.items {
+		if !it.Done && len(out) < size {
+			out = append(out, it)
+		}
+	}
+	return out, nil
+}
+
+func (r *jobRepo) FinishJobItem(it *repository.JobItem) error {
+	cur := &r.items[it.ID-1]
+	if cur.Done {
+		return repository.ErrJobItemDone
+	}
+	cur.Result, cur.Failed, cur.Done = it.Result, it.Failed, true
+	it.Done = true
+	return nil
+}
+
+func (r *jobRepo) JobProgress(jobID uint) (int, int, error) {
+	processed, failed := 0, 0
+	for _, it := range r.items {
+		if it.Done {
+			processed++
+			if it.Failed {
+				failed++
+			}
+		}
+	}
+	return processed, failed, nil
+}
+
+func jobTxs() []dto.Transaction {
+	return []dto.Transaction{
+		{ID: "t1", FromAcc: "A", ToAcc: "B", Amount: money.MustParse("10"), Currency: "USD"},
+		{ID: "t2", FromAcc: "C", ToAcc: "D", Amount: money.MustParse("10.001"), Currency: "USD"}, // invalid
+		{ID: "t3", FromAcc: "E", ToAcc: "F", Amount: money.MustParse("10"), Currency: "USD"},
+	}
+}
+
+// jobService returns a service that screens jobs for owner, one
+// transaction at a time.
+func jobService(repo repository.Repository, owner string) *ComplianceService {
+	s := NewComplianceService(repo)
+	s.BatchWorkers, s.jobOwner = 1, owner
+	return s
+}
+
+func TestRunJob(t *testing.T) {
+	repo := newJobRepo(t, jobTxs()...)
+	s := jobService(repo, "a")
+	j, err := repo.ClaimJob("a", jobLease)
+	if err != nil || j == nil {
+		t.Fatalf("ClaimJob = %v, %v", j, err)
+	}
+	if other, _ := repo.ClaimJob("b", jobLease); other != nil {
+		t.Fatal("a job held by a live lease was claimed by another process")
+	}
+	if err := s.runJob(j); err != nil {
+		t.Fatal(err)
+	}
+	if repo.job.Status != repository.JobCompleted || repo.job.Processed != 3 || repo.job.Failed != 1 {
+		t.Errorf("job = %s, %d processed, %d failed; want completed, 3, 1", repo.job.Status, repo.job.Processed, repo.job.Failed)
+	}
+	if len(repo.audits) != 2 || len(repo.records) != 2 {
+		t.Errorf("%d audits, %d records; want 2 each", len(repo.audits), len(repo.records))
+	}
+	var res BatchResult
+	if err := json.Unmarshal(repo.items[1].Result, &res); err != nil || res.Error == "" || !repo.items[1].Failed {
+		t.Errorf("invalid item result = %s, failed %v; want an error", repo.items[1].Result, repo.items[1].Failed)
+	}
+}
+
+func TestRunJobAfterLeaseExpired(t *testing.T) {
+	repo := newJobRepo(t, jobTxs()...)
+	a := jobService(repo, "a")
+	ja, _ := repo.ClaimJob("a", jobLease)
+	if err := a.saveJob(ja); err != nil {
+		t.Fatal(err)
+	}
+	// a screens the first item, then stalls with the rest of its chunk
+	stale, _ := repo.PendingJobItems(1, jobChunkSize)
+	if err := a.screenItems(ja, stale[:1]); err != nil {
+		t.Fatal(err)
+	}
+
+	repo.now = repo.now.Add(jobLease + time.Second)
+	b := jobService(repo, "b")
+	jb, _ := repo.ClaimJob("b", jobLease)
+	if jb == nil {
+		t.Fatal("a job whose lease expired was not claimed")
+	}
+	if err := b.runJob(jb); err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.audits) != 2 || len(repo.records) != 2 {
+		t.Fatalf("%d audits, %d records after takeover; want 2 each", len(repo.audits), len(repo.records))
+	}
+
+	// a wakes up and screens the chunk it read before losing the lease
+	results := make([]string, len(repo.items))
+	for i, it := range repo.items {
+		results[i] = string(it.Result)
+	}
+	if err := a.screenItems(ja, stale[1:]); err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.audits) != 2 || len(repo.records) != 2 {
+		t.Errorf("%d audits, %d records; items stored by the new owner were validated again", len(repo.audits), len(repo.records))
+	}
+	for i, it := range repo.items {
+		if string(it.Result) != results[i] {
+			t.Errorf("item %d result changed to %s", it.Seq, it.Result)
+		}
+	}
+	if err := a.saveJob(ja); !errors.Is(err, repository.ErrJobLeaseLost) {
+		t.Errorf("saveJob after takeover = %v, want ErrJobLeaseLost", err)
+	}
+	if repo.job.Processed != 3 || repo.job.Failed != 1 || repo.job.Owner != "b" {
+		t.Errorf("job = %d processed, %d failed, owner %q; want 3, 1, b", repo.job.Processed, repo.job.Failed, repo.job.Owner)
+	}
+}
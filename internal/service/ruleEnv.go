@@ -0,0 +1,121 @@
+package service
+
+import (
+	"time"
+
+	"github.com/warleon/ms4-compliance-service/internal/money"
+	"github.com/warleon/ms4-compliance-service/internal/repository"
+	"github.com/warleon/ms4-compliance-service/internal/repository/rules"
+	"gorm.io/gorm"
+)
+
+// ruleEnv gives rule factories access to the repository for one validation.
+// Sanctioned parties and rules are loaded once and reused. When accounts is
+// set, account lookups are answered from it instead of the repository. When
+// at is set, history is read as it stood at that moment.
+type ruleEnv struct {
+	repo repository.Repository
+	at   time.Time
+
+	parties       []rules.Sanction
+	partiesLoaded bool
+	accounts      map[string]struct{}
+	rules         map[uint]*rules.Rule
+}
+
+func newRuleEnv(repo repository.Repository, loaded []rules.Rule) *ruleEnv {
+	e := &ruleEnv{repo: repo, rules: make(map[uint]*rules.Rule, len(loaded))}
+	for i := range loaded {
+		e.rules[loaded[i].ID] = &loaded[i]
+	}
+	return e
+}
+
+func (e *ruleEnv) Now() time.Time {
+	if e.at.IsZero() {
+		return time.Now()
+	}
+	return e.at
+}
+
+func (e *ruleEnv) TransactionStats(q rules.HistoryQuery) (int64, money.Amount, error) {
+	return e.repo.TransactionStats(repository.TransactionFilter{
+		Key:       q.Key,
+		Value:     q.Value,
+		Currency:  q.Currency,
+		Since:     q.Since,
+		Until:     e.at,
+		MinAmount: q.MinAmount,
+		MaxAmount: q.MaxAmount,
+	})
+}
+
+func (e *ruleEnv) SanctionedParties() ([]rules.Sanction, error) {
+	if !e.partiesLoaded {
+		parties, err := e.repo.FindSanctionedParties()
+		if err != nil {
+			return nil, err
+		}
+		e.parties, e.partiesLoaded = parties, true
+	}
+	return e.parties, nil
+}
+
+func (e *ruleEnv) IsAccountSanctioned(accID string) (bool, error) {
+	if e.accounts != nil {
+		_, found := e.accounts[accID]
+		return found, nil
+	}
+	return e.repo.IsAccountSanctioned(accID)
+}
+
+func (e *ruleEnv) Rule(id uint) (*rules.Rule, error) {
+	if r, ok := e.rules[id]; ok {
+		return r, nil
+	}
+	r, err := e.repo.ReadRule(id)
+	if err == gorm.ErrRecordNotFound {
+		r, err = nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	e.rules[id] = r
+	return r, nil
+}
+
+// snapshot holds the rules and sanctions read once for a batch of validations.
+// It is shared by the batch workers and must not be modified.
+type snapshot struct {
+	rules    []rules.Rule
+	parties  []rules.Sanction
+	accounts map[string]struct{}
+}
+
+func loadSnapshot(repo repository.Repository) (*snapshot, error) {
+	all, err := repo.FindRules()
+	if err != nil {
+		return nil, err
+	}
+	parties, err := repo.FindSanctionedParties()
+	if err != nil {
+		return nil, err
+	}
+	accounts, err := repo.ListSanctionedAccounts()
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[string]struct{}, len(accounts))
+	for _, a := range accounts {
+		set[a] = struct{}{}
+	}
+	return &snapshot{rules: all, parties: parties, accounts: set}, nil
+}
+
+// env returns a ruleEnv for one validation that reads from the snapshot.
+func (s *snapshot) env(repo repository.Repository) *ruleEnv {
+	e := newRuleEnv(repo, s.rules)
+	e.parties, e.partiesLoaded = s.parties, true
+	e.accounts = s.accounts
+	return e
+}
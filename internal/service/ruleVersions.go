@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/warleon/ms4-compliance-service/internal/repository"
+	"github.com/warleon/ms4-compliance-service/internal/repository/rules"
+	"gorm.io/gorm"
+)
+
+// ErrRuleVersionNotFound is returned for a rule version that does not exist.
+var ErrRuleVersionNotFound = errors.New("rule version not found")
+
+// RuleChange is a field that differs between two versions of a rule. Field
+// is the JSON name; From or To is null when the field is unset on that side.
+type RuleChange struct {
+	Field string `json:"field"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+}
+
+// bookkeeping fields change with every version and are left out of diffs.
+var bookkeeping = map[string]bool{"ID": true, "CreatedAt": true, "UpdatedAt": true, "DeletedAt": true, "version": true}
+
+// ListRuleVersions returns every version of a rule, newest first.
+func (s *ComplianceService) ListRuleVersions(ctx context.Context, id uint) ([]repository.RuleVersion, error) {
+	return s.Repo.ReadRuleVersions(id)
+}
+
+// DiffRuleVersions lists the fields that changed from version from to
+// version to of a rule, by field name.
+func (s *ComplianceService) DiffRuleVersions(ctx context.Context, id, from, to uint) ([]RuleChange, error) {
+	a, err := s.ruleVersion(id, from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := s.ruleVersion(id, to)
+	if err != nil {
+		return nil, err
+	}
+	fa, err := ruleFields(&a.Snapshot)
+	if err != nil {
+		return nil, err
+	}
+	fb, err := ruleFields(&b.Snapshot)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool)
+	for k := range fa {
+		names[k] = true
+	}
+	for k := range fb {
+		names[k] = true
+	}
+	changes := []RuleChange{}
+	for k := range names {
+		if !bookkeeping[k] && !reflect.DeepEqual(fa[k], fb[k]) {
+			changes = append(changes, RuleChange{Field: k, From: fa[k], To: fb[k]})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes, nil
+}
+
+// RollbackRule makes the settings of an earlier version current again. The
+// rollback is itself recorded as a new version, so history is never lost.
+func (s *ComplianceService) RollbackRule(ctx context.Context, id, version uint) (*rules.Rule, error) {
+	v, err := s.ruleVersion(id, version)
+	if err != nil {
+		return nil, err
+	}
+	r := v.Snapshot
+	if err := r.Check(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRule, err)
+	}
+	if r.Type == rules.RuleTypeComposite {
+		if err := checkChildren(s.Repo, &r); err != nil {
+			return nil, err
+		}
+	}
+	err = s.Repo.WithTx(func(repo repository.Repository) error {
+		cur, err := lockRule(repo, id)
+		if err != nil {
+			return err
+		}
+		r.Model = cur.Model
+		r.Version = cur.Version + 1
+		if err := repo.SaveRule(&r); err != nil {
+			return err
+		}
+		return repo.CreateRuleVersion(repository.NewRuleVersion(&r))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (s *ComplianceService) ruleVersion(id, version uint) (*repository.RuleVersion, error) {
+	v, err := s.Repo.ReadRuleVersion(id, version)
+	if err == gorm.ErrRecordNotFound {
+		return nil, ErrRuleVersionNotFound
+	}
+	return v, err
+}
+
+// ruleFields returns the rule's JSON fields by name.
+func ruleFields(r *rules.Rule) (map[string]any, error) {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	var out map[string]any
+	return out, json.Unmarshal(b, &out)
+}
@@ -2,17 +2,48 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
 
+	"github.com/warleon/ms4-compliance-service/internal/repository"
 	"github.com/warleon/ms4-compliance-service/internal/repository/rules"
+	"gorm.io/gorm"
 )
 
-// Rule CRUD helpers on the service layer. These simply delegate to the repository
-// and exist to provide a clear service boundary and allow future business logic
-// to be applied around rule operations.
+// Rule CRUD on the service layer. Rules are checked before they are stored,
+// composite rules must refer to existing rules without forming a cycle, a
+// rule used by a composite cannot be deleted, and every change is saved as a
+// new version in the same transaction, which DiffRuleVersions and
+// RollbackRule read.
 
-// CreateRule inserts a new rule record.
+// ErrInvalidRule wraps configuration errors reported by rules.Rule.Check.
+var ErrInvalidRule = errors.New("invalid rule")
+
+// ErrRuleNotFound is returned when changing a rule that does not exist.
+var ErrRuleNotFound = errors.New("rule not found")
+
+// ErrRuleInUse is returned when deleting a rule that a composite rule uses.
+var ErrRuleInUse = errors.New("rule in use")
+
+// CreateRule inserts a new rule record as version 1.
 func (s *ComplianceService) CreateRule(ctx context.Context, r *rules.Rule) error {
-	return s.Repo.CreateRule(r)
+	if err := r.Check(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidRule, err)
+	}
+	if r.Type == rules.RuleTypeComposite {
+		if err := checkChildren(s.Repo, r); err != nil {
+			return err
+		}
+	}
+	return s.Repo.WithTx(func(repo repository.Repository) error {
+		r.Version = 1
+		if err := repo.CreateRule(r); err != nil {
+			return err
+		}
+		return repo.CreateRuleVersion(repository.NewRuleVersion(r))
+	})
 }
 
 // GetRule returns a single rule by ID.
@@ -20,17 +51,126 @@ func (s *ComplianceService) GetRule(ctx context.Context, id uint) (*rules.Rule,
 	return s.Repo.ReadRule(id)
 }
 
-// ListRules returns a paginated list of rules.
-func (s *ComplianceService) ListRules(ctx context.Context, size int, offset int) ([]rules.Rule, error) {
-	return s.Repo.ReadRules(size, offset)
+// ListRules returns a paginated list of rules. A period filter is judged at
+// f.At, or now when it is empty.
+func (s *ComplianceService) ListRules(ctx context.Context, f repository.RuleFilter) ([]rules.Rule, error) {
+	if f.At.IsZero() {
+		f.At = time.Now()
+	}
+	return s.Repo.ReadRules(f)
 }
 
-// UpdateRule updates an existing rule.
+// UpdateRule replaces an existing rule with r, as a PUT does, and records
+// the result as a new version. Fields r leaves empty are cleared, so the rule
+// stored is the rule sent. r is validated as a whole and nothing is changed
+// if it is invalid. On return r holds the stored rule.
 func (s *ComplianceService) UpdateRule(ctx context.Context, r *rules.Rule) error {
-	return s.Repo.UpdateRule(r)
+	if err := r.Check(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidRule, err)
+	}
+	return s.Repo.WithTx(func(repo repository.Repository) error {
+		cur, err := lockRule(repo, r.ID)
+		if err != nil {
+			return err
+		}
+		if r.Type == rules.RuleTypeComposite {
+			if err := checkChildren(repo, r); err != nil {
+				return err
+			}
+		}
+		r.Model = cur.Model
+		r.Version = cur.Version + 1
+		if err := repo.SaveRule(r); err != nil {
+			return err
+		}
+		return repo.CreateRuleVersion(repository.NewRuleVersion(r))
+	})
 }
 
-// DeleteRule removes a rule by ID.
+// lockRule locks the rule with the given ID for a change. Rules created
+// before versioning get their current state recorded as version 1 first.
+func lockRule(repo repository.Repository, id uint) (*rules.Rule, error) {
+	cur, err := repo.LockRule(id)
+	if err == gorm.ErrRecordNotFound {
+		return nil, ErrRuleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if cur.Version == 0 {
+		cur.Version = 1
+		if err := repo.CreateRuleVersion(repository.NewRuleVersion(cur)); err != nil {
+			return nil, err
+		}
+	}
+	return cur, nil
+}
+
+// DeleteRule removes a rule by ID and records the deletion as a final
+// version. A rule that a composite rule uses cannot be deleted, as the
+// composite would be skipped from then on.
 func (s *ComplianceService) DeleteRule(ctx context.Context, id uint) error {
-	return s.Repo.DeleteRule(id)
+	return s.Repo.WithTx(func(repo repository.Repository) error {
+		cur, err := lockRule(repo, id)
+		if err != nil {
+			return err
+		}
+		composites, err := repo.FindRulesByType(string(rules.RuleTypeComposite))
+		if err != nil {
+			return err
+		}
+		for _, c := range composites {
+			if slices.Contains(c.Children, id) {
+				return fmt.Errorf("%w: composite rule %d uses rule %d", ErrRuleInUse, c.ID, id)
+			}
+		}
+		cur.Version++
+		if err := repo.SaveRule(cur); err != nil {
+			return err
+		}
+		v := repository.NewRuleVersion(cur)
+		v.Deleted = true
+		if err := repo.CreateRuleVersion(v); err != nil {
+			return err
+		}
+		return repo.DeleteRule(id)
+	})
+}
+
+// checkChildren verifies that the children of composite rule r exist, that
+// following them never leads back to a rule already on the path, r included,
+// and that composites nest at most rules.MaxCompositeDepth levels.
+func checkChildren(repo repository.Repository, r *rules.Rule) error {
+	path := make(map[uint]bool)
+	if r.ID != 0 {
+		path[r.ID] = true
+	}
+	var walk func(ids []uint, depth int) error
+	walk = func(ids []uint, depth int) error {
+		for _, id := range ids {
+			if path[id] {
+				return fmt.Errorf("%w: child rule %d creates a cycle", ErrInvalidRule, id)
+			}
+			child, err := repo.ReadRule(id)
+			if err == gorm.ErrRecordNotFound {
+				return fmt.Errorf("%w: child rule %d not found", ErrInvalidRule, id)
+			}
+			if err != nil {
+				return err
+			}
+			if child.Type != rules.RuleTypeComposite {
+				continue
+			}
+			if depth+1 >= rules.MaxCompositeDepth {
+				return fmt.Errorf("%w: composite rules nest more than %d levels", ErrInvalidRule, rules.MaxCompositeDepth)
+			}
+			path[id] = true
+			if err := walk(child.Children, depth+1); err != nil {
+				return err
+			}
+			delete(path, id)
+		}
+		return nil
+	}
+	return walk(r.Children, 0)
 }
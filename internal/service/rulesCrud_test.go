@@ -0,0 +1,260 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/warleon/ms4-compliance-service/internal/money"
+	"github.com/warleon/ms4-compliance-service/internal/repository"
+	"github.com/warleon/ms4-compliance-service/internal/repository/rules"
+	"gorm.io/gorm"
+)
+
+// ruleRepo is an in-memory repository holding rules. Methods it does not
+// implement panic through the nil embedded Repository.
+type ruleRepo struct {
+	repository.Repository
+	rules    map[uint]*rules.Rule
+	versions []repository.RuleVersion
+}
+
+func newRuleRepo(all ...rules.Rule) *ruleRepo {
+	r := &ruleRepo{rules: make(map[uint]*rules.Rule)}
+	for i := range all {
+		r.rules[all[i].ID] = &all[i]
+	}
+	return r
+}
+
+// WithTx rolls the rules and versions back when fn fails.
+func (r *ruleRepo) WithTx(fn func(repo repository.Repository) error) error {
+	saved := make(map[uint]*rules.Rule, len(r.rules))
+	for id, rule := range r.rules {
+		cp := *rule
+		saved[id] = &cp
+	}
+	versions := len(r.versions)
+	if err := fn(r); err != nil {
+		r.rules, r.versions = saved, r.versions[:versions]
+		return err
+	}
+	return nil
+}
+
+func (r *ruleRepo) CreateRule(rule *rules.Rule) error { return r.SaveRule(rule) }
+
+func (r *ruleRepo) ReadRule(id uint) (*rules.Rule, error) {
+	rule, ok := r.rules[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	cp := *rule
+	return &cp, nil
+}
+
+func (r *ruleRepo) LockRule(id uint) (*rules.Rule, error) { return r.ReadRule(id) }
+
+func (r *ruleRepo) SaveRule(rule *rules.Rule) error {
+	cp := *rule
+	r.rules[rule.ID] = &cp
+	return nil
+}
+
+func (r *ruleRepo) FindRulesByType(t string) ([]rules.Rule, error) {
+	var out []rules.Rule
+	for _, rule := range r.rules {
+		if string(rule.Type) == t {
+			out = append(out, *rule)
+		}
+	}
+	return out, nil
+}
+
+func (r *ruleRepo) DeleteRule(id uint) error {
+	delete(r.rules, id)
+	return nil
+}
+
+func (r *ruleRepo) CreateRuleVersion(v *repository.RuleVersion) error {
+	r.versions = append(r.versions, *v)
+	return nil
+}
+
+func (r *ruleRepo) ReadRuleVersion(id, version uint) (*repository.RuleVersion, error) {
+	for _, v := range r.versions {
+		if v.RuleID == id && v.Version == version {
+			return &v, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func TestDeleteRuleInUse(t *testing.T) {
+	repo := newRuleRepo(
+		amountRule(1, "10", rules.RuleActive),
+		compositeRule(2, rules.CompositeNot, rules.RuleShadow, 1),
+	)
+	s := NewComplianceService(repo)
+
+	if err := s.DeleteRule(context.Background(), 1); !errors.Is(err, ErrRuleInUse) {
+		t.Fatalf("deleting a child: err = %v, want ErrRuleInUse", err)
+	}
+	if _, ok := repo.rules[1]; !ok {
+		t.Fatal("child rule was deleted")
+	}
+	if err := s.DeleteRule(context.Background(), 2); err != nil {
+		t.Fatal(err)
+	}
+	last := repo.versions[len(repo.versions)-1]
+	if last.RuleID != 2 || !last.Deleted || last.Version != 2 || last.Snapshot.Type != rules.RuleTypeComposite {
+		t.Fatalf("last version = %+v, want version 2 of rule 2 marked deleted", last)
+	}
+	if err := s.DeleteRule(context.Background(), 1); err != nil {
+		t.Fatalf("deleting a rule no longer in use: %v", err)
+	}
+	if err := s.DeleteRule(context.Background(), 1); !errors.Is(err, ErrRuleNotFound) {
+		t.Fatalf("deleting a deleted rule: err = %v, want ErrRuleNotFound", err)
+	}
+}
+
+func TestUpdateRuleReplacesRule(t *testing.T) {
+	ctx := context.Background()
+	until := time.Now().Add(time.Hour)
+	weight := 30.0
+	stored := amountRule(1, "10", rules.RuleActive)
+	stored.Version, stored.EffectiveTo, stored.Weight = 1, &until, &weight
+	stored.Schedule = []rules.TimeWindow{{Start: "22:00", End: "06:00"}}
+	repo := newRuleRepo(stored, amountRule(2, "20", rules.RuleActive), compositeRule(4, rules.CompositeOr, rules.RuleActive, 1))
+	s := NewComplianceService(repo)
+
+	// fields left out of the body are cleared
+	up := amountRule(1, "0", rules.RuleActive)
+	if err := s.UpdateRule(ctx, &up); err != nil {
+		t.Fatal(err)
+	}
+	got := repo.rules[1]
+	if got.Version != 2 || got.EffectiveTo != nil || got.Weight != nil || got.Schedule != nil || !got.Threshold.IsZero() {
+		t.Fatalf("updated rule = %+v, want the empty fields cleared", got)
+	}
+	if v := repo.versions[len(repo.versions)-1]; v.Version != 2 || !reflect.DeepEqual(v.Snapshot, *got) {
+		t.Fatalf("version 2 = %+v, want the stored rule", v)
+	}
+
+	// a composite's children can be cleared only by replacing them
+	up = compositeRule(4, rules.CompositeAnd, rules.RuleActive, 2)
+	if err := s.UpdateRule(ctx, &up); err != nil {
+		t.Fatal(err)
+	}
+	if c := repo.rules[4]; len(c.Children) != 1 || c.Children[0] != 2 || *c.Operator != rules.CompositeAnd {
+		t.Fatalf("composite = %+v, want and over rule 2", c)
+	}
+
+	for _, tc := range []struct {
+		name string
+		rule rules.Rule
+	}{
+		{"not with two children", compositeRule(4, rules.CompositeNot, rules.RuleActive, 1, 2)},
+		{"composite without children", compositeRule(4, rules.CompositeOr, rules.RuleActive)},
+		{"composite containing itself", compositeRule(4, rules.CompositeOr, rules.RuleActive, 4)},
+		{"unknown child", compositeRule(4, rules.CompositeOr, rules.RuleActive, 9)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			versions := len(repo.versions)
+			if err := s.UpdateRule(ctx, &tc.rule); !errors.Is(err, ErrInvalidRule) {
+				t.Fatalf("err = %v, want ErrInvalidRule", err)
+			}
+			if c := repo.rules[4]; *c.Operator != rules.CompositeAnd || len(repo.versions) != versions {
+				t.Fatalf("invalid update was stored: %+v", c)
+			}
+		})
+	}
+
+	missing := amountRule(7, "10", rules.RuleActive)
+	if err := s.UpdateRule(ctx, &missing); !errors.Is(err, ErrRuleNotFound) {
+		t.Fatalf("updating a missing rule: err = %v, want ErrRuleNotFound", err)
+	}
+}
+
+func TestDiffAndRollbackRule(t *testing.T) {
+	ctx := context.Background()
+	repo := newRuleRepo()
+	s := NewComplianceService(repo)
+	weight := 30.0
+	r := amountRule(0, "10", rules.RuleActive)
+	r.ID, r.Weight = 1, &weight
+	if err := s.CreateRule(ctx, &r); err != nil {
+		t.Fatal(err)
+	}
+	up := amountRule(1, "25", rules.RuleShadow)
+	if err := s.UpdateRule(ctx, &up); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := s.DiffRuleVersions(ctx, 1, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields []string
+	for _, c := range changes {
+		fields = append(fields, c.Field)
+	}
+	if !reflect.DeepEqual(fields, []string{"Threshold", "status", "weight"}) {
+		t.Fatalf("changed fields = %v, want Threshold, status and weight", fields)
+	}
+	if changes[2].From != 30.0 || changes[2].To != nil {
+		t.Fatalf("weight change = %+v, want 30 to null", changes[2])
+	}
+	if _, err := s.DiffRuleVersions(ctx, 1, 1, 5); !errors.Is(err, ErrRuleVersionNotFound) {
+		t.Fatalf("diff with a missing version: err = %v, want ErrRuleVersionNotFound", err)
+	}
+
+	back, err := s.RollbackRule(ctx, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := repo.rules[1]
+	if back.Version != 3 || got.Version != 3 || got.Threshold.Cmp(money.MustParse("10")) != 0 ||
+		got.Weight == nil || *got.Weight != 30 || got.Status != rules.RuleActive {
+		t.Fatalf("rolled back rule = %+v, want version 1's settings as version 3", got)
+	}
+	if changes, _ := s.DiffRuleVersions(ctx, 1, 1, 3); len(changes) != 0 {
+		t.Fatalf("version 3 differs from version 1: %+v", changes)
+	}
+}
+
+func TestCheckChildren(t *testing.T) {
+	ctx := context.Background()
+	// rules 101 to 100+n are composites nested around rule 1
+	chain := func(n int) *ruleRepo {
+		repo := newRuleRepo(amountRule(1, "10", rules.RuleActive))
+		child := uint(1)
+		for i := range n {
+			c := compositeRule(uint(101+i), rules.CompositeOr, rules.RuleActive, child)
+			repo.rules[c.ID] = &c
+			child = c.ID
+		}
+		return repo
+	}
+
+	top := compositeRule(0, rules.CompositeOr, rules.RuleActive, uint(100+rules.MaxCompositeDepth-1))
+	if err := NewComplianceService(chain(rules.MaxCompositeDepth-1)).CreateRule(ctx, &top); err != nil {
+		t.Fatalf("composite %d levels deep: %v", rules.MaxCompositeDepth, err)
+	}
+	top = compositeRule(0, rules.CompositeOr, rules.RuleActive, uint(100+rules.MaxCompositeDepth))
+	if err := NewComplianceService(chain(rules.MaxCompositeDepth)).CreateRule(ctx, &top); !errors.Is(err, ErrInvalidRule) {
+		t.Fatalf("composite %d levels deep: err = %v, want ErrInvalidRule", rules.MaxCompositeDepth+1, err)
+	}
+
+	// 101 uses 1 and 102 uses 101; making 101 use 102 closes a cycle
+	repo := chain(2)
+	up := compositeRule(101, rules.CompositeOr, rules.RuleActive, 102)
+	if err := NewComplianceService(repo).UpdateRule(ctx, &up); !errors.Is(err, ErrInvalidRule) {
+		t.Fatalf("cycle: err = %v, want ErrInvalidRule", err)
+	}
+	if c := repo.rules[101]; c.Children[0] != 1 {
+		t.Fatalf("cycle was stored: %+v", c)
+	}
+}
@@ -0,0 +1,258 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/warleon/ms4-compliance-service/internal/repository"
+	"github.com/warleon/ms4-compliance-service/internal/repository/rules"
+)
+
+// Bulk upload formats accepted by ImportSanctions.
+const (
+	SanctionFormatCSV   = "csv"
+	SanctionFormatJSONL = "jsonl"
+)
+
+const (
+	// maxAccIDLength matches the size of the Sanction.AccID column.
+	maxAccIDLength = 100
+	// sanctionLookupChunk bounds the IN list when checking for duplicates.
+	sanctionLookupChunk = 1000
+	// maxReportedRejections caps how many rejected lines are listed in a bulk result.
+	maxReportedRejections = 100
+)
+
+var (
+	ErrInvalidSanction   = errors.New("invalid account identifier")
+	ErrDuplicateSanction = errors.New("account is already sanctioned")
+)
+
+// BulkRejection describes an input line that could not be imported.
+type BulkRejection struct {
+	Line   int    `json:"line"`
+	Reason string `json:"reason"`
+}
+
+// BulkResult summarises a bulk sanctions upload. Rejections lists at most
+// maxReportedRejections entries; Rejected is always the full count.
+type BulkResult struct {
+	Added      int             `json:"added"`
+	Duplicated int             `json:"duplicated"`
+	Rejected   int             `json:"rejected"`
+	Rejections []BulkRejection `json:"rejections,omitempty"`
+}
+
+func (b *BulkResult) reject(line int, reason string) {
+	b.Rejected++
+	if len(b.Rejections) < maxReportedRejections {
+		b.Rejections = append(b.Rejections, BulkRejection{Line: line, Reason: reason})
+	}
+}
+
+func validAccID(id string) bool {
+	return id != "" && len(id) <= maxAccIDLength
+}
+
+// CreateSanction adds a single account to the sanctions list.
+func (s *ComplianceService) CreateSanction(ctx context.Context, sanction *rules.Sanction) error {
+	sanction.AccID = strings.TrimSpace(sanction.AccID)
+	if !validAccID(sanction.AccID) {
+		return ErrInvalidSanction
+	}
+	sanction.Source = rules.SanctionSourceManual
+	return s.Repo.WithTx(func(repo repository.Repository) error {
+		existing, err := repo.FindSanctionedAccounts([]string{sanction.AccID})
+		if err != nil {
+			return err
+		}
+		if len(existing) > 0 {
+			return ErrDuplicateSanction
+		}
+		return repo.CreateSanction(sanction)
+	})
+}
+
+// GetSanction returns a single sanctions entry by ID.
+func (s *ComplianceService) GetSanction(ctx context.Context, id uint) (*rules.Sanction, error) {
+	return s.Repo.ReadSanction(id)
+}
+
+// ListSanctions returns a paginated list of sanctions entries.
+func (s *ComplianceService) ListSanctions(ctx context.Context, size int, offset int) ([]rules.Sanction, error) {
+	return s.Repo.ReadSanctions(size, offset)
+}
+
+// DeleteSanction removes a sanctions entry by ID.
+func (s *ComplianceService) DeleteSanction(ctx context.Context, id uint) error {
+	return s.Repo.DeleteSanction(id)
+}
+
+// ImportSanctions reads account identifiers in the given format and adds the
+// new ones in a single database transaction. Identifiers already sanctioned,
+// or repeated within the upload, are counted as duplicates.
+//
+// CSV takes the identifier from the first column; a header row named accId or
+// acc_id is skipped. JSONL expects one {"accId": "..."} object per line.
+func (s *ComplianceService) ImportSanctions(ctx context.Context, format string, r io.Reader) (*BulkResult, error) {
+	res := &BulkResult{}
+	var ids []string
+	var err error
+	switch format {
+	case SanctionFormatCSV:
+		ids, err = readSanctionsCSV(r, res)
+	case SanctionFormatJSONL:
+		ids, err = readSanctionsJSONL(r, res)
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// drop repeats within the upload itself
+	seen := make(map[string]bool, len(ids))
+	unique := ids[:0]
+	for _, id := range ids {
+		if seen[id] {
+			res.Duplicated++
+			continue
+		}
+		seen[id] = true
+		unique = append(unique, id)
+	}
+
+	err = s.Repo.WithTx(func(repo repository.Repository) error {
+		existing := make(map[string]bool)
+		for start := 0; start < len(unique); start += sanctionLookupChunk {
+			end := min(start+sanctionLookupChunk, len(unique))
+			found, err := repo.FindSanctionedAccounts(unique[start:end])
+			if err != nil {
+				return err
+			}
+			for _, id := range found {
+				existing[id] = true
+			}
+		}
+		var toAdd []rules.Sanction
+		for _, id := range unique {
+			if existing[id] {
+				res.Duplicated++
+				continue
+			}
+			toAdd = append(toAdd, rules.Sanction{AccID: id, Source: rules.SanctionSourceManual})
+		}
+		if err := repo.CreateSanctions(toAdd); err != nil {
+			return err
+		}
+		res.Added = len(toAdd)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func readSanctionsCSV(r io.Reader, res *BulkResult) ([]string, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	var ids []string
+	for first := true; ; first = false {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var perr *csv.ParseError
+			if errors.As(err, &perr) {
+				res.reject(perr.StartLine, perr.Err.Error())
+				continue
+			}
+			return nil, err
+		}
+		line, _ := cr.FieldPos(0)
+		id := strings.TrimSpace(rec[0])
+		if first && (strings.EqualFold(id, "accId") || strings.EqualFold(id, "acc_id")) {
+			continue
+		}
+		if !validAccID(id) {
+			res.reject(line, ErrInvalidSanction.Error())
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func readSanctionsJSONL(r io.Reader, res *BulkResult) ([]string, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	var ids []string
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" {
+			continue
+		}
+		var entry struct {
+			AccID string `json:"accId"`
+		}
+		if err := json.Unmarshal([]byte(text), &entry); err != nil {
+			res.reject(line, "invalid JSON")
+			continue
+		}
+		id := strings.TrimSpace(entry.AccID)
+		if !validAccID(id) {
+			res.reject(line, ErrInvalidSanction.Error())
+			continue
+		}
+		ids = append(ids, id)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// ListImportResult summarises an official sanctions list import.
+type ListImportResult struct {
+	Source   rules.SanctionSource `json:"source"`
+	Imported int                  `json:"imported"`
+	Removed  int64                `json:"removed"`
+}
+
+// ImportSanctionList replaces every entry previously imported from source with
+// entries, in a single transaction. Official lists are published as complete
+// snapshots, so parties delisted since the last import disappear.
+func (s *ComplianceService) ImportSanctionList(ctx context.Context, source rules.SanctionSource, entries []rules.Sanction) (*ListImportResult, error) {
+	if source == rules.SanctionSourceManual || source == "" {
+		return nil, fmt.Errorf("cannot replace %q sanctions with a list import", source)
+	}
+	res := &ListImportResult{Source: source}
+	err := s.Repo.WithTx(func(repo repository.Repository) error {
+		removed, err := repo.DeleteSanctionsBySource(source)
+		if err != nil {
+			return err
+		}
+		for i := range entries {
+			entries[i].Source = source
+		}
+		if err := repo.CreateSanctions(entries); err != nil {
+			return err
+		}
+		res.Removed = removed
+		res.Imported = len(entries)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/warleon/ms4-compliance-service/internal/repository"
+	"github.com/warleon/ms4-compliance-service/internal/repository/rules"
+)
+
+// sanctionRepo is an in-memory sanctions list. CreateSanctions fails with
+// createErr when it is set.
+type sanctionRepo struct {
+	repository.Repository
+	sanctions []rules.Sanction
+	createErr error
+}
+
+// WithTx rolls the list back when fn fails.
+func (r *sanctionRepo) WithTx(fn func(repo repository.Repository) error) error {
+	saved := len(r.sanctions)
+	if err := fn(r); err != nil {
+		r.sanctions = r.sanctions[:saved]
+		return err
+	}
+	return nil
+}
+
+func (r *sanctionRepo) FindSanctionedAccounts(accIDs []string) ([]string, error) {
+	var out []string
+	for _, s := range r.sanctions {
+		for _, id := range accIDs {
+			if s.AccID == id {
+				out = append(out, id)
+			}
+		}
+	}
+	return out, nil
+}
+
+func (r *sanctionRepo) CreateSanction(s *rules.Sanction) error {
+	return r.CreateSanctions([]rules.Sanction{*s})
+}
+
+func (r *sanctionRepo) CreateSanctions(s []rules.Sanction) error {
+	r.sanctions = append(r.sanctions, s...)
+	return r.createErr
+}
+
+func (r *sanctionRepo) accIDs() []string {
+	var out []string
+	for _, s := range r.sanctions {
+		out = append(out, s.AccID)
+	}
+	return out
+}
+
+func TestCreateSanction(t *testing.T) {
+	repo := &sanctionRepo{}
+	s := NewComplianceService(repo)
+	for _, tc := range []struct {
+		accID string
+		err   error
+	}{
+		{"  ACC-1 ", nil},
+		{"ACC-1", ErrDuplicateSanction},
+		{"   ", ErrInvalidSanction},
+		{strings.Repeat("x", maxAccIDLength+1), ErrInvalidSanction},
+	} {
+		sanction := &rules.Sanction{AccID: tc.accID, Source: "ofac"}
+		if err := s.CreateSanction(context.Background(), sanction); !errors.Is(err, tc.err) {
+			t.Errorf("CreateSanction(%.20q) = %v, want %v", tc.accID, err, tc.err)
+		}
+	}
+	if len(repo.sanctions) != 1 || repo.sanctions[0].AccID != "ACC-1" || repo.sanctions[0].Source != rules.SanctionSourceManual {
+		t.Errorf("sanctions = %+v, want ACC-1 entered manually", repo.sanctions)
+	}
+}
+
+func TestImportSanctions(t *testing.T) {
+	for _, tc := range []struct {
+		name, format, in string
+		added            []string
+		want             BulkResult
+	}{
+		{
+			name:   "csv",
+			format: SanctionFormatCSV,
+			in:     "accId,note\nACC-1,x\n ACC-2\nACC-1\nOLD\n\"\",empty\n",
+			added:  []string{"ACC-1", "ACC-2"},
+			want:   BulkResult{Added: 2, Duplicated: 2, Rejected: 1, Rejections: []BulkRejection{{Line: 6, Reason: ErrInvalidSanction.Error()}}},
+		},
+		{
+			name:   "csv without header",
+			format: SanctionFormatCSV,
+			in:     "ACC-1\nACC-2\n",
+			added:  []string{"ACC-1", "ACC-2"},
+			want:   BulkResult{Added: 2},
+		},
+		{
+			name:   "malformed csv line",
+			format: SanctionFormatCSV,
+			in:     "ACC-1\n\"ACC\"2\"\nACC-3\n",
+			added:  []string{"ACC-1", "ACC-3"},
+			want:   BulkResult{Added: 2, Rejected: 1, Rejections: []BulkRejection{{Line: 2, Reason: `extraneous or missing " in quoted-field`}}},
+		},
+		{
+			name:   "jsonl",
+			format: SanctionFormatJSONL,
+			in:     "{\"accId\":\"ACC-1\"}\n\n{\"accId\": \"OLD\"}\nnot json\n{\"name\":\"x\"}\n",
+			added:  []string{"ACC-1"},
+			want: BulkResult{Added: 1, Duplicated: 1, Rejected: 2, Rejections: []BulkRejection{
+				{Line: 4, Reason: "invalid JSON"},
+				{Line: 5, Reason: ErrInvalidSanction.Error()},
+			}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &sanctionRepo{sanctions: []rules.Sanction{{AccID: "OLD"}}}
+			s := NewComplianceService(repo)
+			res, err := s.ImportSanctions(context.Background(), tc.format, strings.NewReader(tc.in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(*res, tc.want) {
+				t.Errorf("result = %+v, want %+v", *res, tc.want)
+			}
+			if got := repo.accIDs()[1:]; !reflect.DeepEqual(got, tc.added) {
+				t.Errorf("added %v, want %v", got, tc.added)
+			}
+		})
+	}
+}
+
+func TestImportSanctionsFailures(t *testing.T) {
+	s := NewComplianceService(&sanctionRepo{})
+	if _, err := s.ImportSanctions(context.Background(), "xml", strings.NewReader("")); err == nil {
+		t.Error("an unsupported format was accepted")
+	}
+
+	// a failed insert adds nothing
+	repo := &sanctionRepo{createErr: errors.New("insert failed")}
+	s = NewComplianceService(repo)
+	if _, err := s.ImportSanctions(context.Background(), SanctionFormatCSV, strings.NewReader("ACC-1\nACC-2\n")); !errors.Is(err, repo.createErr) {
+		t.Fatalf("err = %v, want the insert error", err)
+	}
+	if len(repo.sanctions) != 0 {
+		t.Errorf("%d sanctions kept after a failed upload", len(repo.sanctions))
+	}
+
+	// every rejected line is counted, but only the first ones are listed
+	repo = &sanctionRepo{}
+	s = NewComplianceService(repo)
+	in := strings.Repeat("not json\n", maxReportedRejections+5)
+	res, err := s.ImportSanctions(context.Background(), SanctionFormatJSONL, strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Rejected != maxReportedRejections+5 || len(res.Rejections) != maxReportedRejections {
+		t.Errorf("%d rejected, %d listed; want %d and %d", res.Rejected, len(res.Rejections), maxReportedRejections+5, maxReportedRejections)
+	}
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/warleon/ms4-compliance-service/internal/repository"
+	"github.com/warleon/ms4-compliance-service/internal/repository/rules"
+)
+
+// Shadow rule metrics, served with the other expvars on /debug/vars.
+// shadow_rule_outcomes counts results by "<rule id>/<status>";
+// shadow_rule_changes counts, by rule ID, the results that would have made
+// the decision stricter.
+var (
+	shadowOutcomes = expvar.NewMap("shadow_rule_outcomes")
+	shadowChanges  = expvar.NewMap("shadow_rule_changes")
+)
+
+// maxShadowSamples caps the audit IDs a report lists per rule.
+const maxShadowSamples = 10
+
+// recordShadow updates the shadow rule metrics for a committed decision.
+func recordShadow(dec *rules.Decision, trace []rules.RuleResult) {
+	for _, r := range trace {
+		if !r.Shadow || r.Skipped {
+			continue
+		}
+		shadowOutcomes.Add(fmt.Sprintf("%d/%s", r.RuleID, r.Status), 1)
+		if r.Status.Outranks(dec.Status) {
+			shadowChanges.Add(fmt.Sprint(r.RuleID), 1)
+		}
+	}
+}
+
+// ShadowReport compares the results of shadow rules with the live decisions
+// recorded in the audit log over [From, To).
+type ShadowReport struct {
+	From   time.Time          `json:"from"`
+	To     time.Time          `json:"to"`
+	Audits int                `json:"audits"` // audit entries with shadow results
+	Rules  []ShadowRuleReport `json:"rules"`
+}
+
+// ShadowRuleReport summarizes one shadow rule. Comparisons counts each pair
+// of live decision and shadow outcome; Changes counts the evaluations where
+// the shadow outcome is stricter than the live decision, which is how the
+// decision would have changed had the rule been active. Samples lists audit
+// IDs of such changes.
+type ShadowRuleReport struct {
+	RuleID      uint               `json:"ruleId"`
+	Name        string             `json:"name"`
+	Evaluated   int                `json:"evaluated"`
+	Skipped     int                `json:"skipped"`
+	Comparisons []ShadowComparison `json:"comparisons"`
+	Changes     int                `json:"changes"`
+	Samples     []uint             `json:"samples,omitempty"`
+}
+
+// ShadowComparison counts evaluations with a given live and shadow outcome.
+type ShadowComparison struct {
+	Live   rules.DecisionStatus `json:"live"`
+	Shadow rules.DecisionStatus `json:"shadow"`
+	Count  int                  `json:"count"`
+}
+
+// ShadowReport scans the audit entries created in [from, to) that carry
+// shadow results, optionally for a single rule.
+func (s *ComplianceService) ShadowReport(ctx context.Context, from, to time.Time, ruleID uint) (*ShadowReport, error) {
+	report := &ShadowReport{From: from, To: to, Rules: []ShadowRuleReport{}}
+	byRule := make(map[uint]*ShadowRuleReport)
+	pairs := make(map[uint]map[[2]rules.DecisionStatus]int)
+	f := repository.AuditFilter{Shadow: true, RuleID: ruleID, From: &from, To: &to, Size: 500}
+	for {
+		page, err := s.Repo.FindAudits(f)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range page {
+			report.Audits++
+			for _, r := range a.Trace {
+				if !r.Shadow || (ruleID != 0 && r.RuleID != ruleID) {
+					continue
+				}
+				rr, ok := byRule[r.RuleID]
+				if !ok {
+					rr = &ShadowRuleReport{RuleID: r.RuleID, Name: r.Name}
+					byRule[r.RuleID] = rr
+					pairs[r.RuleID] = make(map[[2]rules.DecisionStatus]int)
+				}
+				if r.Skipped {
+					rr.Skipped++
+					continue
+				}
+				rr.Evaluated++
+				pairs[r.RuleID][[2]rules.DecisionStatus{a.Decision.Status, r.Status}]++
+				if r.Status.Outranks(a.Decision.Status) {
+					rr.Changes++
+					if len(rr.Samples) < maxShadowSamples {
+						rr.Samples = append(rr.Samples, a.ID)
+					}
+				}
+			}
+		}
+		if len(page) < f.Size {
+			break
+		}
+		f.Cursor = page[len(page)-1].ID
+	}
+	for id, rr := range byRule {
+		for p, n := range pairs[id] {
+			rr.Comparisons = append(rr.Comparisons, ShadowComparison{Live: p[0], Shadow: p[1], Count: n})
+		}
+		sort.Slice(rr.Comparisons, func(i, j int) bool {
+			a, b := rr.Comparisons[i], rr.Comparisons[j]
+			if a.Live != b.Live {
+				return a.Live < b.Live
+			}
+			return a.Shadow < b.Shadow
+		})
+		report.Rules = append(report.Rules, *rr)
+	}
+	sort.Slice(report.Rules, func(i, j int) bool { return report.Rules[i].RuleID < report.Rules[j].RuleID })
+	return report, nil
+}